@@ -1,26 +1,199 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"os"
 
 	"github.com/LaoZhuBaba/arithmetic_parser/internal/app"
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/calc"
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/format"
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/language"
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/lexer"
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/parser"
 )
 
+// Exit statuses, so that scripts can tell the kinds of failure apart.
+const (
+	exitOK         = 0
+	exitFailure    = 1 // exitFailure is used for I/O errors and any other failure, such as a script with no value
+	exitUsage      = 2 // exitUsage is used for invalid flags, including format options, or a missing expression
+	exitSyntax     = 3 // exitSyntax is used for expressions that are malformed
+	exitEvaluation = 4 // exitEvaluation is used for well-formed expressions that can't be evaluated, e.g. 1/0
+)
+
+// implicitModes maps the values accepted by --implicit to the TokenId inserted between juxtaposed operands.
+var implicitModes = map[string]lexer.TokenId{
+	"off":   lexer.NullToken,
+	"same":  lexer.Multiply,
+	"tight": lexer.ImplicitMultiply,
+}
+
+// quiet suppresses error messages, leaving only the exit status.
+var quiet bool
+
 func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	var opts app.Options
+
+	flag.IntVar(&opts.Format.Base, "base", 10, "output base: 2, 8, 10 or 16")
+	notation := flag.String("format", "plain", "output notation: plain, sci or eng")
+	flag.StringVar(&opts.Format.Separator, "separator", "", "separator inserted between groups of digits, e.g. ','")
+	flag.IntVar(&opts.Format.Precision, "precision", format.AutoPrecision,
+		"number of decimal places, or -1 for as many as needed")
+	mode := flag.String("mode", "int", "numeric mode: int or float")
+	implicit := flag.String("implicit", "off",
+		"implicit multiplication such as 2(3+4): off (unless the dialect enables it), same (as *) or tight (binds more tightly than *)")
+	file := flag.String("file", "", "evaluate the expressions in a file, one per line, or - for standard input")
+	jsonLines := flag.Bool("jsonl", false, "write batch results as JSON Lines")
+	flag.BoolVar(&quiet, "quiet", false, "don't write error messages; only set the exit status")
+	configFile := flag.String("config", "", "read the language definition (tokens, operators and groups) from a JSON file")
+	flag.BoolVar(&opts.Explain, "explain", false, "print each step of the evaluation before the result")
+	flag.BoolVar(&opts.Canonical, "canonical", false, "print the expression in canonical form instead of evaluating it")
+	flag.BoolVar(&opts.RPN, "rpn", false, "read the expression in Reverse Polish notation, e.g. '1 2 + 3 *'")
+	flag.StringVar(&opts.Export, "export", "",
+		"print the expression in rpn or polish notation, or compiled to json, instead of evaluating it")
+	flag.BoolVar(&opts.Compiled, "compiled", false, "evaluate an expression compiled by --export=json")
+	dialect := flag.String("dialect", "", fmt.Sprintf("use a preset language: one of %v", language.Dialects))
+	flag.Parse()
+
+	var (
+		err error
+		ok  bool
+	)
+
+	opts.ImplicitMultiplication, ok = implicitModes[*implicit]
+	if !ok {
+		return fail(exitUsage, fmt.Errorf("invalid value for --implicit: %q", *implicit))
+	}
+
+	opts.Format.Notation, err = format.ParseNotation(*notation)
+	if err != nil {
+		return fail(exitUsage, err)
+	}
+
+	opts.Mode, err = calc.ParseMode(*mode)
+	if err != nil {
+		return fail(exitUsage, err)
+	}
+
+	if opts.Export != "" && opts.Export != "rpn" && opts.Export != "polish" && opts.Export != "json" {
+		return fail(exitUsage, fmt.Errorf("invalid value for --export: %q", opts.Export))
+	}
+
+	if opts.Compiled && (opts.RPN || opts.Canonical || opts.Explain || opts.Export != "") {
+		return fail(exitUsage, errors.New("--compiled cannot be used with --rpn, --canonical, --explain or --export"))
+	}
+
+	if *configFile != "" && *dialect != "" {
+		return fail(exitUsage, errors.New("--config and --dialect cannot be used together"))
+	}
+
+	if *configFile != "" {
+		opts.Language, err = language.LoadFile(*configFile)
+		if err != nil {
+			return fail(exitUsage, err)
+		}
+	}
+
+	if *dialect != "" {
+		opts.Language, err = language.Dialect(*dialect)
+		if err != nil {
+			return fail(exitUsage, err)
+		}
+	}
+
 	var input string
-	for _, arg := range os.Args[1:] {
+	for _, arg := range flag.Args() {
 		input += arg
 	}
 
+	if opts.Explain && len(flag.Args()) == 0 {
+		return fail(exitUsage, errors.New("--explain needs an expression on the command line"))
+	}
+
+	// With no expression on the command line, evaluate a file or piped input, or else run interactively.
+	if *file != "" || len(flag.Args()) == 0 && !app.IsTerminal(os.Stdin) {
+		return runBatch(*file, opts, *jsonLines)
+	}
+
+	if len(flag.Args()) == 0 {
+		if opts.Canonical || opts.Export != "" || opts.RPN || opts.Compiled {
+			return fail(exitUsage, errors.New("--canonical, --export, --rpn and --compiled need an expression or --file"))
+		}
+
+		err = app.REPL(os.Stdin, os.Stdout, opts, app.HistoryPath())
+		if err != nil {
+			return fail(exitFailure, err)
+		}
+
+		return exitOK
+	}
+
 	if input == "" {
-		fmt.Println("no expression provided")
-		return
+		return fail(exitUsage, errors.New("no expression provided"))
 	}
 
-	err := app.Calculate(input)
+	err = app.Calculate(input, opts)
 	if err != nil {
-		fmt.Printf("calculation failed with error: %v", err)
-		return
+		return fail(exitCode(err), fmt.Errorf("calculation failed with error: %w", err))
+	}
+
+	return exitOK
+}
+
+// runBatch evaluates every line of the named file, or of standard input if name is "" or "-",
+// and returns the exit status, which reflects the first expression to fail.
+func runBatch(name string, opts app.Options, jsonLines bool) int {
+	in := os.Stdin
+
+	if name != "" && name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return fail(exitFailure, err)
+		}
+		defer f.Close()
+
+		in = f
 	}
+
+	summary, err := app.Batch(in, os.Stdout, opts, jsonLines)
+	if err != nil {
+		return fail(exitFailure, err)
+	}
+
+	if summary.Failed > 0 {
+		return fail(exitCode(summary.FirstError),
+			fmt.Errorf("%d of %d expressions failed", summary.Failed, summary.Total))
+	}
+
+	return exitOK
+}
+
+// exitCode returns the exit status for an error returned while evaluating an expression and formatting
+// its result.
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, parser.ErrEvaluation):
+		return exitEvaluation
+	case errors.Is(err, parser.ErrSyntax):
+		return exitSyntax
+	case errors.Is(err, format.ErrInvalidOptions):
+		return exitUsage
+	default:
+		return exitFailure
+	}
+}
+
+// fail writes err to standard error, unless --quiet was given, and returns status.
+func fail(status int, err error) int {
+	if !quiet {
+		fmt.Fprintln(os.Stderr, err)
+	}
+
+	return status
 }
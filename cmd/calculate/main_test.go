@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/calc"
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/format"
+)
+
+func TestExitCode(t *testing.T) {
+	evaluate := func(expr string, opts ...calc.Option) error {
+		_, err := calc.Evaluate(expr, opts...)
+		return err
+	}
+
+	formatInt := func(o format.Options) error {
+		_, err := format.Int(5, o)
+		return err
+	}
+
+	_, openErr := os.Open("testdata/missing")
+
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "lexer error", err: evaluate("3 $ 4"), want: exitSyntax},
+		{name: "parse error", err: evaluate("1+"), want: exitSyntax},
+		{name: "not an expression", err: func() error { _, err := calc.RPN("x = 1"); return err }(), want: exitSyntax},
+		{name: "evaluation error", err: evaluate("1/0"), want: exitEvaluation},
+		{name: "undefined variable", err: evaluate("y"), want: exitEvaluation},
+		{name: "invalid base", err: formatInt(format.Options{Base: 3}), want: exitUsage},
+		{name: "unsupported format", err: formatInt(format.Options{Base: 16, Notation: format.Scientific}), want: exitUsage},
+		{name: "wrapped", err: fmt.Errorf("calculation failed with error: %w", evaluate("1+")), want: exitSyntax},
+		{name: "no value", err: evaluate("f(x) = x"), want: exitFailure},
+		{name: "I/O error", err: openErr, want: exitFailure},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.err == nil {
+				t.Fatalf("test case has no error")
+			}
+
+			if got := exitCode(tt.err); got != tt.want {
+				t.Fatalf("exitCode(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -29,7 +29,7 @@ func FuzzLexAndEval(f *testing.F) {
 		{Id: lexer.RParen, Value: ")"},
 	}
 
-	operations := []parser.Operation{
+	operations := []parser.Operation[int]{
 		{Description: "Plus", TokenId: lexer.Plus, Fn: func(a, b int) (int, error) { return a + b, nil }},
 		{Description: "Minus", TokenId: lexer.Minus, Fn: func(a, b int) (int, error) { return a - b, nil }},
 		{Description: "Multiply", TokenId: lexer.Multiply, Fn: func(a, b int) (int, error) { return a * b, nil }},
@@ -46,7 +46,12 @@ func FuzzLexAndEval(f *testing.F) {
 		{Tokens: []lexer.TokenId{lexer.Multiply, lexer.Divide}, Precedence: parser.PrecedenceMultiplyDivide, Associativity: parser.LeftAssociative},
 		{Tokens: []lexer.TokenId{lexer.Plus, lexer.Minus}, Precedence: parser.PrecedencePlusMinus, Associativity: parser.LeftAssociative},
 	}
-	p := parser.NewParser(operations, opGroups)
+	// A small MaxDepth lets seeds within the 512 byte input limit nest deeper than it.
+	const maxDepth = 100
+	p, err := parser.NewParser(operations, opGroups, parser.WithLimits(parser.Limits{MaxDepth: maxDepth}))
+	if err != nil {
+		f.Fatalf("NewParser() err=%v", err)
+	}
 
 	// Seed corpus: valid, invalid, whitespacey, precedence, parentheses, associativity, etc.
 	seeds := []string{
@@ -69,6 +74,16 @@ func FuzzLexAndEval(f *testing.F) {
 		"2^3^2",       // right associativity: 2^(3^2)
 		"(2^3)^2",     // parentheses override associativity
 		"2^(3^2)",     // explicit right association
+		"0x1F+0b1010", // prefixed literals
+		"1_000*0o17",  // digit separators and octal
+		"0x",          // malformed literal
+		// Deep nesting, at and beyond maxDepth, balanced and not.
+		strings.Repeat("(", maxDepth) + "1" + strings.Repeat(")", maxDepth),
+		strings.Repeat("(", maxDepth+1) + "1" + strings.Repeat(")", maxDepth+1),
+		strings.Repeat("(", 250) + "1",
+		"1" + strings.Repeat(")", 250),
+		strings.Repeat("(1+", 150) + "1" + strings.Repeat(")", 150),
+		strings.Repeat("2^(", 120) + "1" + strings.Repeat(")", 120),
 	}
 	for _, s := range seeds {
 		f.Add(s)
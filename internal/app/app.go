@@ -1,31 +1,163 @@
 package app
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
 
-	"github.com/LaoZhuBaba/arithmetic_parser/internal/app/config"
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/calc"
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/format"
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/language"
 	"github.com/LaoZhuBaba/arithmetic_parser/pkg/lexer"
-	"github.com/LaoZhuBaba/arithmetic_parser/pkg/parser"
 )
 
-func Calculate(s string) error {
-	lx := lexer.NewLexer(s, config.Tokens)
+// Options holds the settings selected on the command line.
+type Options struct {
+	Mode   calc.Mode
+	Format format.Options
+	// Language replaces language.Default if it isn't nil.
+	Language *language.Language
+	// ImplicitMultiplication is the TokenId used for juxtaposed operands such as 2(3+4), or
+	// lexer.NullToken to leave the choice to the Language, which rejects them by default.
+	// See parser.WithImplicitMultiplication.
+	ImplicitMultiplication lexer.TokenId
+	// Explain prints each step of the evaluation before the result.  Only Calculate uses it.
+	Explain bool
+	// Canonical prints the expression in canonical form instead of evaluating it.  See calc.Canonical.
+	Canonical bool
+	// RPN reads the expression in Reverse Polish notation.  See calc.FromRPN.
+	RPN bool
+	// Export prints the expression in "rpn" or "polish" notation, or compiled to "json", instead of
+	// evaluating it.  See calc.RPN and calc.Compile.
+	Export string
+	// Compiled reads the expression as JSON written by calc.Compile.  See calc.EvaluateCompiled.
+	Compiled bool
+}
 
-	elements, err := lx.GetElementList()
-	if err != nil {
-		fmt.Printf("lexer failed with error: %v", err)
+var errInvalidExport = errors.New("invalid export notation")
 
-		return err
+// Calculate evaluates s and prints the formatted result, preceded by the steps taken to reach it if
+// opts.Explain is set.  If opts.Canonical is set, s is printed in canonical form instead, and if opts.Export
+// is set, in that notation.  If opts.RPN is set, s is read in Reverse Polish notation, and if opts.Compiled
+// is set, s is a compiled expression, which is evaluated.
+func Calculate(s string, opts Options) error {
+	if !opts.Explain || opts.Canonical || opts.Export != "" {
+		output, err := evaluate(s, opts, nil)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(output)
+
+		return nil
 	}
 
-	pa := parser.NewParser(config.Operations, config.OpGroup)
+	var trace calc.Trace
+
+	eval := calc.Evaluate
+	if opts.RPN {
+		eval = calc.EvaluateRPN
+	}
+
+	result, err := eval(s, append(opts.calcOptions(nil), calc.WithTrace(&trace))...)
+
+	// The steps are printed even if evaluation failed, to show how far it got.
+	explain(os.Stdout, s, trace)
 
-	result, err := pa.Eval(elements)
 	if err != nil {
 		return err
 	}
 
-	fmt.Println(*result)
+	output, err := result.Format(opts.Format)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(output)
 
 	return nil
 }
+
+// convert returns s in the notation selected by opts.Export, or in canonical form if it isn't set.
+func convert(s string, opts Options) (string, error) {
+	switch opts.Export {
+	case "":
+		return calc.Canonical(s, opts.calcOptions(nil)...)
+	case "rpn":
+		return calc.RPN(s, opts.calcOptions(nil)...)
+	case "polish":
+		return calc.Polish(s, opts.calcOptions(nil)...)
+	case "json":
+		data, err := calc.Compile(s, opts.calcOptions(nil)...)
+		return string(data), err
+	default:
+		return "", fmt.Errorf("%w: %q", errInvalidExport, opts.Export)
+	}
+}
+
+// explain writes each Step of trace to w, followed by the part of s it reduced, e.g.
+//
+//	3*4 = 12    [4:7] "3*4"
+func explain(w io.Writer, s string, trace calc.Trace) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, step := range trace.Steps {
+		fmt.Fprintf(tw, "%s\t[%d:%d] %q\n", step, step.Span.Start, step.Span.End, s[step.Span.Start:step.Span.End])
+	}
+
+	tw.Flush()
+}
+
+// evaluate evaluates s with variables taken from env, which may be nil, and returns the formatted result,
+// or s converted as opts.Canonical or opts.Export asks.  s is read as opts.RPN or opts.Compiled say.
+func evaluate(s string, opts Options, env *calc.Environment) (string, error) {
+	var (
+		result calc.Value
+		err    error
+	)
+
+	switch {
+	case opts.Canonical || opts.Export != "":
+		if opts.RPN {
+			// FromRPN's result is already in canonical form, which might not be read back if it has a
+			// negative number that the language can only write in RPN.
+			s, err = calc.FromRPN(s, opts.calcOptions(env)...)
+			if err != nil || opts.Export == "" {
+				return s, err
+			}
+		}
+
+		return convert(s, opts)
+	case opts.RPN:
+		result, err = calc.EvaluateRPN(s, opts.calcOptions(env)...)
+	case opts.Compiled:
+		result, err = calc.EvaluateCompiled([]byte(s), opts.calcOptions(env)...)
+	default:
+		result, err = calc.Evaluate(s, opts.calcOptions(env)...)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return result.Format(opts.Format)
+}
+
+// calcOptions converts opts to calc Options, with variables taken from env.
+func (opts Options) calcOptions(env *calc.Environment) []calc.Option {
+	calcOpts := []calc.Option{
+		calc.WithMode(opts.Mode),
+		calc.WithEnvironment(env),
+	}
+
+	if opts.Language != nil {
+		calcOpts = append(calcOpts, calc.WithLanguage(opts.Language))
+	}
+
+	if opts.ImplicitMultiplication != lexer.NullToken {
+		calcOpts = append(calcOpts, calc.WithImplicitMultiplication(opts.ImplicitMultiplication))
+	}
+
+	return calcOpts
+}
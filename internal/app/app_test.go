@@ -0,0 +1,26 @@
+package app
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/calc"
+)
+
+func TestExplain(t *testing.T) {
+	const input = "2 + 3*4"
+
+	var trace calc.Trace
+
+	if _, err := calc.Evaluate(input, calc.WithTrace(&trace)); err != nil {
+		t.Fatalf("Evaluate() err=%v", err)
+	}
+
+	var out bytes.Buffer
+	explain(&out, input, trace)
+
+	want := "3*4 = 12   [4:7] \"3*4\"\n2+12 = 14  [0:7] \"2 + 3*4\"\n"
+	if out.String() != want {
+		t.Fatalf("explain() wrote %q, want %q", out.String(), want)
+	}
+}
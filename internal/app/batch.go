@@ -0,0 +1,127 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// maxLineLength is the length in bytes of the longest line that Batch and the REPL will evaluate.
+const maxLineLength = 1 << 20
+
+var errLineTooLong = fmt.Errorf("line too long: the limit is %d bytes", maxLineLength)
+
+// batchResult is one line of JSON Lines output.
+type batchResult struct {
+	Input  string `json:"input"`
+	Result string `json:"result,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// BatchSummary describes the outcome of a call to Batch.
+type BatchSummary struct {
+	Total  int
+	Failed int
+	// FirstError is the error from the first expression that failed, or nil.
+	FirstError error
+}
+
+// Batch evaluates each non-blank line read from in as a separate expression and writes one result or
+// error per line to out, either as plain text or, if jsonLines is set, as JSON Lines with input, result
+// and error fields.  Each line is read and converted as in Calculate, so with opts.Canonical the result is
+// the line in canonical form.  A line longer than maxLineLength fails without being evaluated, and its
+// input is reported cut short.  The returned error is only set if reading or writing fails.
+func Batch(in io.Reader, out io.Writer, opts Options, jsonLines bool) (summary BatchSummary, err error) {
+	r := bufio.NewReader(in)
+	w := bufio.NewWriter(out)
+	enc := json.NewEncoder(w)
+
+	for {
+		line, readErr := readLine(r)
+		if errors.Is(readErr, io.EOF) {
+			break
+		}
+
+		if readErr != nil && !errors.Is(readErr, errLineTooLong) {
+			return summary, readErr
+		}
+
+		input := strings.TrimSpace(line)
+		if input == "" {
+			continue
+		}
+
+		summary.Total++
+
+		result, evalErr := "", readErr
+		if evalErr == nil {
+			result, evalErr = evaluate(input, opts, nil)
+		}
+
+		if evalErr != nil {
+			summary.Failed++
+			if summary.FirstError == nil {
+				summary.FirstError = evalErr
+			}
+		}
+
+		switch {
+		case jsonLines:
+			r := batchResult{Input: input, Result: result}
+			if evalErr != nil {
+				r.Error = evalErr.Error()
+			}
+
+			err = enc.Encode(r)
+		case evalErr != nil:
+			_, err = fmt.Fprintf(w, "error: %v\n", evalErr)
+		default:
+			_, err = fmt.Fprintln(w, result)
+		}
+
+		if err != nil {
+			return summary, err
+		}
+	}
+
+	return summary, w.Flush()
+}
+
+// readLine returns the next line from r without its line ending, or io.EOF if there are none.  Only the
+// first maxLineLength bytes of a longer line are returned, with errLineTooLong, and the rest is skipped.
+func readLine(r *bufio.Reader) (string, error) {
+	var line []byte
+
+	read := 0
+
+	for {
+		chunk, err := r.ReadSlice('\n')
+		read += len(chunk)
+
+		// Enough is kept to tell whether the line is too long once its line ending is removed.
+		if room := maxLineLength + len("\r\n") - len(line); room > 0 {
+			line = append(line, chunk[:min(len(chunk), room)]...)
+		}
+
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+
+		if err != nil && (!errors.Is(err, io.EOF) || read == 0) {
+			return "", err
+		}
+
+		break
+	}
+
+	line = bytes.TrimSuffix(bytes.TrimSuffix(line, []byte("\n")), []byte("\r"))
+	if len(line) > maxLineLength {
+		return string(line[:maxLineLength]), errLineTooLong
+	}
+
+	return string(line), nil
+}
@@ -0,0 +1,112 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/calc"
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/format"
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/parser"
+)
+
+func TestBatch(t *testing.T) {
+	input := "1+1\n\n  2*3  \n1/0\n3+\n"
+
+	tests := []struct {
+		name      string
+		opts      Options
+		jsonLines bool
+		want      string
+	}{
+		{
+			name: "plain text",
+			opts: Options{Format: format.Default},
+			want: "2\n6\nerror: evaluation error: division by zero\nerror: syntax error: index out of range: with index 1 and elements: [3 +]\n",
+		},
+		{
+			name:      "json lines",
+			opts:      Options{Format: format.Default},
+			jsonLines: true,
+			want: `{"input":"1+1","result":"2"}
+{"input":"2*3","result":"6"}
+{"input":"1/0","error":"evaluation error: division by zero"}
+{"input":"3+","error":"syntax error: index out of range: with index 1 and elements: [3 +]"}
+`,
+		},
+		{
+			name: "float mode",
+			opts: Options{Mode: calc.FloatMode, Format: format.Default},
+			want: "2\n6\nerror: evaluation error: division by zero\nerror: syntax error: index out of range: with index 1 and elements: [3 +]\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+
+			summary, err := Batch(strings.NewReader(input), &out, tt.opts, tt.jsonLines)
+			if err != nil {
+				t.Fatalf("Batch() err=%v", err)
+			}
+			if summary.Total != 4 || summary.Failed != 2 || !errors.Is(summary.FirstError, parser.ErrEvaluation) {
+				t.Fatalf("Batch() summary=%+v, want 4 total and 2 failed, starting with an evaluation error", summary)
+			}
+			if out.String() != tt.want {
+				t.Fatalf("Batch() output=%q want=%q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestBatch_LongLine(t *testing.T) {
+	// The line too long for a default bufio.Scanner is evaluated, the one too long for Batch fails alone, and
+	// the last line is read without a line ending.
+	input := "1+1\r\n" + strings.Repeat(" ", maxLineLength-1) + "7\n" + strings.Repeat("1+", maxLineLength) + "1\n2*3"
+
+	var out bytes.Buffer
+
+	summary, err := Batch(strings.NewReader(input), &out, Options{Format: format.Default}, false)
+	if err != nil {
+		t.Fatalf("Batch() err=%v", err)
+	}
+	if summary.Total != 4 || summary.Failed != 1 || !errors.Is(summary.FirstError, errLineTooLong) {
+		t.Fatalf("Batch() summary=%+v, want 4 total and 1 failed with a line too long", summary)
+	}
+
+	want := "2\n7\nerror: " + errLineTooLong.Error() + "\n6\n"
+	if out.String() != want {
+		t.Fatalf("Batch() output=%q want=%q", out.String(), want)
+	}
+}
+
+func TestBatch_Convert(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		opts  Options
+		want  string
+	}{
+		{name: "canonical", input: "((1+2))*3\n2^(3^2)\n", opts: Options{Canonical: true}, want: "(1 + 2) * 3\n2 ^ 3 ^ 2\n"},
+		{name: "export", input: "(1+2)*3\n", opts: Options{Export: "polish"}, want: "* + 1 2 3\n"},
+		{name: "rpn", input: "1 2 + 3 *\n2 3 2 ^ ^\n", opts: Options{RPN: true, Format: format.Default}, want: "9\n512\n"},
+		{name: "rpn to canonical", input: "1 2 + 3 *\n", opts: Options{RPN: true, Canonical: true}, want: "(1 + 2) * 3\n"},
+		{name: "compiled", input: `{"version":1,"nodes":[{"type":"number","value":"7"}]}` + "\n", opts: Options{Compiled: true, Format: format.Default}, want: "7\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+
+			summary, err := Batch(strings.NewReader(tt.input), &out, tt.opts, false)
+			if err != nil || summary.Failed != 0 {
+				t.Fatalf("Batch() = (%+v, %v)", summary, err)
+			}
+
+			if out.String() != tt.want {
+				t.Fatalf("Batch() output=%q want=%q", out.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package app
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// The control keys understood by lineEditor.
+const (
+	keyCtrlA     = 0x01
+	keyCtrlB     = 0x02
+	keyCtrlC     = 0x03
+	keyCtrlD     = 0x04
+	keyCtrlE     = 0x05
+	keyCtrlF     = 0x06
+	keyBackspace = 0x08
+	keyCtrlK     = 0x0b
+	keyCtrlN     = 0x0e
+	keyCtrlP     = 0x10
+	keyCtrlU     = 0x15
+	keyEscape    = 0x1b
+	keyDelete    = 0x7f
+	// keyForwardDelete stands for the Delete key, which sends an escape sequence rather than a single rune.
+	keyForwardDelete = -1
+)
+
+// lineReader reads the lines of an interactive session, returning io.EOF when there are no more.
+type lineReader interface {
+	readLine(prompt string) (string, error)
+}
+
+// newLineReader returns a lineEditor if in is a terminal that can be switched to raw mode, and otherwise
+// a lineScanner.  history holds the lines of earlier sessions, oldest first.  The returned function
+// restores the terminal and must be called when the session ends.
+func newLineReader(in io.Reader, out io.Writer, history []string) (lineReader, func()) {
+	if f, ok := in.(*os.File); ok {
+		if restore, err := makeRaw(f); err == nil {
+			return &lineEditor{in: bufio.NewReader(f), out: out, history: history}, restore
+		}
+	}
+
+	return &lineScanner{in: bufio.NewReader(in), out: out}, func() {}
+}
+
+// lineScanner reads lines that have already been edited, such as those of a terminal in its normal mode
+// or of piped input.  A line longer than maxLineLength gives errLineTooLong, and the next line can still be
+// read.
+type lineScanner struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+func (s *lineScanner) readLine(prompt string) (string, error) {
+	fmt.Fprint(s.out, prompt)
+
+	return readLine(s.in)
+}
+
+// lineEditor reads lines from a terminal in raw mode and echoes them itself, so that the cursor can be
+// moved with the arrow keys, Home and End or Ctrl-B, Ctrl-F, Ctrl-A and Ctrl-E, text deleted with
+// Backspace, Delete, Ctrl-K and Ctrl-U, and earlier lines recalled with Up and Down or Ctrl-P and Ctrl-N.
+// Ctrl-C abandons the line and Ctrl-D on an empty line ends the session.  Each line entered is added to
+// history unless it is blank or repeats the previous one.
+type lineEditor struct {
+	in      *bufio.Reader
+	out     io.Writer
+	history []string
+}
+
+func (e *lineEditor) readLine(prompt string) (string, error) {
+	var line, draft []rune
+
+	pos := 0
+	// recalled is the index in history of the line being edited, or len(history) for a new line, which is
+	// kept in draft while earlier lines are recalled.
+	recalled := len(e.history)
+
+	recall := func(i int) {
+		if i < 0 || i > len(e.history) || i == recalled {
+			return
+		}
+
+		if recalled == len(e.history) {
+			draft = line
+		}
+
+		if recalled = i; i == len(e.history) {
+			line = draft
+		} else {
+			line = []rune(e.history[i])
+		}
+
+		pos = len(line)
+	}
+
+	fmt.Fprint(e.out, prompt)
+
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return "", err
+		}
+
+		key := r
+		if r == keyEscape {
+			key = e.escape()
+		}
+
+		switch key {
+		case '\r', '\n':
+			fmt.Fprintln(e.out)
+
+			s := string(line)
+			if strings.TrimSpace(s) != "" && (len(e.history) == 0 || e.history[len(e.history)-1] != s) {
+				e.history = append(e.history, s)
+			}
+
+			return s, nil
+		case keyCtrlC:
+			fmt.Fprintln(e.out, "^C")
+			return "", nil
+		case keyCtrlD:
+			if len(line) == 0 {
+				return "", io.EOF
+			}
+
+			fallthrough
+		case keyForwardDelete:
+			if pos < len(line) {
+				line = slices.Delete(line, pos, pos+1)
+			}
+		case keyBackspace, keyDelete:
+			if pos > 0 {
+				line = slices.Delete(line, pos-1, pos)
+				pos--
+			}
+		case keyCtrlA:
+			pos = 0
+		case keyCtrlE:
+			pos = len(line)
+		case keyCtrlB:
+			pos = max(pos-1, 0)
+		case keyCtrlF:
+			pos = min(pos+1, len(line))
+		case keyCtrlK:
+			line = line[:pos]
+		case keyCtrlU:
+			line, pos = slices.Clone(line[pos:]), 0
+		case keyCtrlP:
+			recall(recalled - 1)
+		case keyCtrlN:
+			recall(recalled + 1)
+		default:
+			if key != keyEscape && unicode.IsPrint(key) {
+				line = slices.Insert(line, pos, key)
+				pos++
+			}
+		}
+
+		// Redraw the line, then move the cursor back from its end to pos.
+		fmt.Fprintf(e.out, "\r%s%s\x1b[K", prompt, string(line))
+
+		if n := len(line) - pos; n > 0 {
+			fmt.Fprintf(e.out, "\x1b[%dD", n)
+		}
+	}
+}
+
+// escape reads the rest of an escape sequence sent by a key and returns the control key with the same
+// effect, keyForwardDelete for Delete, or keyEscape for keys that do nothing.
+func (e *lineEditor) escape() rune {
+	if r, _, err := e.in.ReadRune(); err != nil || r != '[' && r != 'O' {
+		return keyEscape
+	}
+
+	// The sequence ends with a byte between @ and ~, after any parameters.
+	var seq strings.Builder
+
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return keyEscape
+		}
+
+		seq.WriteRune(r)
+
+		if r >= '@' && r <= '~' {
+			break
+		}
+	}
+
+	switch seq.String() {
+	case "A":
+		return keyCtrlP
+	case "B":
+		return keyCtrlN
+	case "C":
+		return keyCtrlF
+	case "D":
+		return keyCtrlB
+	case "H", "1~", "7~":
+		return keyCtrlA
+	case "F", "4~", "8~":
+		return keyCtrlE
+	case "3~":
+		return keyForwardDelete
+	default:
+		return keyEscape
+	}
+}
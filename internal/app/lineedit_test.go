@@ -0,0 +1,74 @@
+package app
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLineEditor(t *testing.T) {
+	tests := []struct {
+		name    string
+		history []string
+		input   string
+		want    string
+		wantErr error
+	}{
+		{name: "plain", input: "1+2\r", want: "1+2"},
+		{name: "newline", input: "1+2\n", want: "1+2"},
+		{name: "insert after left arrow", input: "12\x1b[D3\r", want: "132"},
+		{name: "home and end", input: "2\x1b[H-\x1b[F!\r", want: "-2!"},
+		{name: "control keys", input: "23\x01\x06\x021\x05+\r", want: "123+"},
+		{name: "backspace", input: "12\x7f3\x08\x084\r", want: "4"},
+		{name: "delete", input: "123\x01\x1b[3~\x04\r", want: "3"},
+		{name: "kill", input: "1+2*3\x02\x02\x0b\x01\x06\x15\r", want: "+2"},
+		{name: "recall", history: []string{"1+1", "x = 2"}, input: "\x1b[A\x1b[A*2\r", want: "1+1*2"},
+		{name: "recall with control keys", history: []string{"1+1", "x = 2"}, input: "\x10\x10\x10\x0e\r", want: "x = 2"},
+		{name: "draft restored", history: []string{"1+1"}, input: "7\x1b[A\x1b[B8\r", want: "78"},
+		{name: "unknown sequence", input: "1\x1b[5~\x1bx2\r", want: "12"},
+		{name: "interrupt", input: "1+\x03", want: ""},
+		{name: "end of input", input: "\x04", wantErr: io.EOF},
+		{name: "unfinished line", input: "1+2", wantErr: io.EOF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+
+			e := lineEditor{in: bufio.NewReader(strings.NewReader(tt.input)), out: &out, history: tt.history}
+
+			got, err := e.readLine("> ")
+			if !errors.Is(err, tt.wantErr) || got != tt.want {
+				t.Fatalf("readLine() = (%q, %v), want (%q, %v)", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestLineEditor_History checks that entered lines can be recalled, except blank ones and repeats.
+func TestLineEditor_History(t *testing.T) {
+	var out strings.Builder
+
+	e := lineEditor{in: bufio.NewReader(strings.NewReader("1\r \r1\r2\r\x1b[A\x1b[A\r")), out: &out}
+
+	for range 4 {
+		if _, err := e.readLine("> "); err != nil {
+			t.Fatalf("readLine() err=%v", err)
+		}
+	}
+
+	if got, err := e.readLine("> "); err != nil || got != "1" {
+		t.Fatalf("readLine() = (%q, %v), want 1", got, err)
+	}
+
+	if strings.Join(e.history, "|") != "1|2|1" {
+		t.Fatalf("history=%q", e.history)
+	}
+
+	// The line is redrawn after each key.
+	if !strings.HasSuffix(out.String(), "\r> 1\x1b[K\n") {
+		t.Fatalf("output=%q", out.String())
+	}
+}
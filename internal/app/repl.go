@@ -0,0 +1,241 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/calc"
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/lexer"
+)
+
+// ansVariable always holds the previous result in an interactive session.
+const ansVariable = "ans"
+
+const replHelp = `Enter an expression to evaluate it, "name = expression" to assign a variable, or
+"name(x, y) = expression" to define a function.  Separate several statements on one line with ";".
+Built-in functions include sqrt, ln, log(base, x), sin, round(x, digits), min, max and gcd, and the
+constants pi, e, tau and phi can't be reassigned.
+The previous result is available as "ans".
+
+Commands:
+  :help              show this help
+  :vars              list variables
+  :mode [int|float]  show or change the numeric mode
+  :history           show previous input
+  :quit              leave the calculator
+`
+
+// session holds the state of an interactive session.  Variables keep the mode they were assigned in
+// and are converted when used, so they persist across mode switches.
+type session struct {
+	opts        Options
+	env         *calc.Environment
+	historyPath string
+}
+
+// HistoryPath returns the file used to persist interactive input: $CALCULATE_HISTORY if set,
+// otherwise .calculate_history in the user's home directory.
+func HistoryPath() string {
+	if path := os.Getenv("CALCULATE_HISTORY"); path != "" {
+		return path
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(home, ".calculate_history")
+}
+
+// REPL runs an interactive session, reading lines from in and writing prompts and results to out until
+// in is exhausted or the user enters :quit.  Each line is appended to the file at historyPath unless
+// historyPath is empty.  If in is a terminal, lines are edited as lineEditor describes, and the lines of
+// earlier sessions can be recalled; otherwise line editing is left to the terminal.
+func REPL(in io.Reader, out io.Writer, opts Options, historyPath string) error {
+	s := session{
+		opts:        opts,
+		env:         calc.NewEnvironment(),
+		historyPath: historyPath,
+	}
+
+	var (
+		history  io.Writer = io.Discard
+		previous []string
+	)
+
+	if historyPath != "" {
+		b, err := os.ReadFile(historyPath)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+
+		previous = strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+
+		f, err := os.OpenFile(historyPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		history = f
+	}
+
+	lines, restore := newLineReader(in, out, slices.DeleteFunc(previous, func(line string) bool { return line == "" }))
+	defer restore()
+
+	for {
+		line, err := lines.readLine("> ")
+		if errors.Is(err, errLineTooLong) {
+			fmt.Fprintf(out, "error: %v\n", err)
+			continue
+		}
+
+		if err != nil {
+			fmt.Fprintln(out)
+
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+
+			return err
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		if _, err := fmt.Fprintln(history, line); err != nil {
+			return err
+		}
+
+		if line == ":quit" || line == ":exit" {
+			return nil
+		}
+
+		output, err := s.execute(line)
+		if err != nil {
+			fmt.Fprintf(out, "error: %v\n", err)
+			continue
+		}
+
+		if output != "" {
+			fmt.Fprintln(out, output)
+		}
+	}
+}
+
+// execute runs a single line of input and returns the text to display.
+func (s *session) execute(line string) (string, error) {
+	if strings.HasPrefix(line, ":") {
+		return s.command(strings.Fields(line[1:]))
+	}
+
+	// Assignments are stored in s.env by calc.Evaluate.
+	result, err := calc.Evaluate(line, s.opts.calcOptions(s.env)...)
+	if errors.Is(err, calc.ErrNoValue) {
+		// The line only defined functions.
+		return "", nil
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	s.env.Set(ansVariable, result)
+
+	// A line holding a single assignment echoes the name.
+	name := ansVariable
+	if lhs, _, found := strings.Cut(line, "="); found && !strings.Contains(line, ";") && isIdentifier(lhs) {
+		name = strings.TrimSpace(lhs)
+	}
+
+	output, err := result.Format(s.opts.Format)
+	if err != nil || name == ansVariable {
+		return output, err
+	}
+
+	return name + " = " + output, nil
+}
+
+// command runs a REPL command, given the words that followed the colon.
+func (s *session) command(words []string) (string, error) {
+	if len(words) == 0 {
+		return "", fmt.Errorf("missing command, try :help")
+	}
+
+	switch words[0] {
+	case "help":
+		return strings.TrimSuffix(replHelp, "\n"), nil
+	case "vars":
+		return s.listVariables()
+	case "mode":
+		if len(words) > 1 {
+			mode, err := calc.ParseMode(words[1])
+			if err != nil {
+				return "", err
+			}
+
+			s.opts.Mode = mode
+		}
+
+		return "mode is " + s.opts.Mode.String(), nil
+	case "history":
+		if s.historyPath == "" {
+			return "", nil
+		}
+
+		b, err := os.ReadFile(s.historyPath)
+		if err != nil {
+			return "", err
+		}
+
+		return strings.TrimSuffix(string(b), "\n"), nil
+	default:
+		return "", fmt.Errorf("unknown command :%s, try :help", words[0])
+	}
+}
+
+// listVariables returns one "name = value" line for each variable, converted to the current mode,
+// followed by one "name(params) = body" line for each function.
+func (s *session) listVariables() (string, error) {
+	lines := make([]string, 0, len(s.env.Names())+len(s.env.FunctionNames()))
+
+	for _, name := range s.env.Names() {
+		v, _ := s.env.Get(name)
+
+		output, err := v.In(s.opts.Mode).Format(s.opts.Format)
+		if err != nil {
+			return "", err
+		}
+
+		lines = append(lines, name+" = "+output)
+	}
+
+	for _, name := range s.env.FunctionNames() {
+		fn, _ := s.env.Function(name)
+
+		definition, err := calc.Definition(name, fn, s.opts.calcOptions(s.env)...)
+		if err != nil {
+			return "", err
+		}
+
+		lines = append(lines, definition)
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// isIdentifier reports whether s, ignoring surrounding spaces, is a single identifier.
+func isIdentifier(s string) bool {
+	elements, err := lexer.NewLexer(s, nil, lexer.WithIdentifiers()).GetElementList()
+
+	return err == nil && len(elements) == 1 && elements[0].Token == lexer.Identifier
+}
@@ -0,0 +1,122 @@
+package app
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/format"
+)
+
+func TestREPL(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "expressions and ans",
+			input: "2+3\nans*2\n",
+			want:  []string{"5", "10"},
+		},
+		{
+			name:  "assignment persists across lines",
+			input: "rate = 3\nrate*4\n:vars\n",
+			want:  []string{"rate = 3", "12", "ans = 12\nrate = 3"},
+		},
+		{
+			name:  "several statements on a line",
+			input: "a = 2; b = a*3; a+b\n:vars\n",
+			want:  []string{"8", "a = 2\nans = 8\nb = 6"},
+		},
+		{
+			name:  "functions persist across lines",
+			input: "f(x, y) = x^2 + y\nf(3, 4)\n:vars\nf(1)\n",
+			want:  []string{"13", "ans = 13\nf(x, y) = x ^ 2 + y", "error: evaluation error: wrong number of arguments: f takes 2, got 1"},
+		},
+		{
+			name:  "functions are listed in canonical form",
+			input: "g(x)=x²+(1)\n:vars\n",
+			want:  []string{"g(x) = x ^ 2 + 1"},
+		},
+		{
+			name:  "mode switch converts variables",
+			input: "x = 7/2\n:mode float\nx/2\n:mode int\nans\n",
+			want:  []string{"x = 3", "mode is float", "1.5", "mode is int", "1"},
+		},
+		{
+			name:  "errors don't end the session",
+			input: "y\n1/0\n:nonsense\n2 = 3\n4\n",
+			want: []string{
+				"error: evaluation error: undefined identifier: y",
+				"error: evaluation error: division by zero",
+				"error: unknown command :nonsense, try :help",
+				`error: syntax error: cannot assign to "2"`,
+				"4",
+			},
+		},
+		{
+			name:  "long lines don't end the session",
+			input: strings.Repeat("1+", maxLineLength) + "1\n2\n",
+			want:  []string{"error: " + errLineTooLong.Error(), "2"},
+		},
+		{
+			name:  "quit stops reading",
+			input: "1\n:quit\n2\n",
+			want:  []string{"1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+
+			err := REPL(strings.NewReader(tt.input), &out, Options{Format: format.Default}, "")
+			if err != nil {
+				t.Fatalf("REPL() err=%v", err)
+			}
+
+			// Every output follows a prompt, so splitting on the prompt separates them.
+			var got []string
+			for _, s := range strings.Split(out.String(), "> ") {
+				if s = strings.TrimSuffix(s, "\n"); s != "" {
+					got = append(got, s)
+				}
+			}
+
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Fatalf("REPL() output=%q want=%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestREPL_History(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+
+	if err := REPL(strings.NewReader("1+1\n\nx = 2\n"), &bytes.Buffer{}, Options{}, path); err != nil {
+		t.Fatalf("REPL() err=%v", err)
+	}
+
+	// A second session appends to the same file and can show it.
+	var out bytes.Buffer
+	if err := REPL(strings.NewReader("3\n:history\n"), &out, Options{}, path); err != nil {
+		t.Fatalf("REPL() err=%v", err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "1+1\nx = 2\n3\n:history\n"
+	if string(b) != want {
+		t.Fatalf("history file=%q want=%q", b, want)
+	}
+
+	if !strings.Contains(out.String(), "1+1\nx = 2\n3\n:history") {
+		t.Fatalf(":history output=%q", out.String())
+	}
+}
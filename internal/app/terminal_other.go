@@ -0,0 +1,22 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+
+package app
+
+import (
+	"errors"
+	"os"
+)
+
+// makeRaw fails, since raw mode isn't supported on this system, so the REPL leaves line editing to the
+// terminal.
+func makeRaw(*os.File) (func(), error) {
+	return nil, errors.New("raw terminal mode isn't supported")
+}
+
+// IsTerminal reports whether f is a character device, which is the closest this system comes to telling
+// whether it is a terminal.
+func IsTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,46 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package app
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// makeRaw turns off f's line buffering, echo and signal keys so that lineEditor can edit lines itself,
+// and returns a function that restores f's previous settings.  It fails if f isn't a terminal.
+func makeRaw(f *os.File) (func(), error) {
+	fd := f.Fd()
+
+	var old syscall.Termios
+	if err := termios(fd, ioctlGetTermios, &old); err != nil {
+		return nil, err
+	}
+
+	raw := old
+	raw.Lflag &^= syscall.ICANON | syscall.ECHO | syscall.ISIG | syscall.IEXTEN
+	raw.Cc[syscall.VMIN], raw.Cc[syscall.VTIME] = 1, 0
+
+	if err := termios(fd, ioctlSetTermios, &raw); err != nil {
+		return nil, err
+	}
+
+	return func() { _ = termios(fd, ioctlSetTermios, &old) }, nil
+}
+
+// IsTerminal reports whether f is a terminal, as opposed to a pipe, a file or a device such as /dev/null.
+func IsTerminal(f *os.File) bool {
+	var t syscall.Termios
+
+	return termios(f.Fd(), ioctlGetTermios, &t) == nil
+}
+
+// termios gets or sets the terminal settings of fd, as req says.
+func termios(fd uintptr, req uintptr, t *syscall.Termios) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, req, uintptr(unsafe.Pointer(t))); errno != 0 {
+		return errno
+	}
+
+	return nil
+}
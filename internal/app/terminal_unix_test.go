@@ -0,0 +1,21 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package app
+
+import (
+	"os"
+	"testing"
+)
+
+func TestIsTerminal(t *testing.T) {
+	// /dev/null is a character device, but not a terminal, so input from it is read in batch mode.
+	f, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if IsTerminal(f) {
+		t.Fatalf("IsTerminal(%s) = true, want false", os.DevNull)
+	}
+}
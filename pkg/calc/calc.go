@@ -0,0 +1,367 @@
+// Package calc evaluates arithmetic expressions given as strings.  It combines the lexer and
+// parser packages with the default configuration, any part of which can be replaced with Options.
+package calc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/language"
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/lexer"
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/parser"
+)
+
+var modeNames = map[string]Mode{
+	"int":   IntegerMode,
+	"float": FloatMode,
+}
+
+var errInvalidMode = errors.New("invalid mode")
+
+// ErrNoValue is returned by Evaluate for a script that only defines functions, such as "f(x) = 2x".
+// The definitions are still stored in the Environment given by WithEnvironment.
+var ErrNoValue = errors.New("script has no value")
+
+// ParseMode converts a mode name ("int" or "float") to a Mode.
+func ParseMode(s string) (Mode, error) {
+	m, ok := modeNames[s]
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", errInvalidMode, s)
+	}
+
+	return m, nil
+}
+
+func (m Mode) String() string {
+	for name, mode := range modeNames {
+		if mode == m {
+			return name
+		}
+	}
+
+	return fmt.Sprintf("Mode(%d)", int8(m))
+}
+
+// WithMode selects the numeric mode.  The default is IntegerMode.
+func WithMode(mode Mode) Option {
+	return func(s *settings) {
+		s.mode = mode
+	}
+}
+
+// WithTokens replaces the default operator spellings used by the lexer.  Superscript digits are read as an
+// exponent if tokens spell lexer.Exponent, and are invalid otherwise.
+func WithTokens(tokens []lexer.Token) Option {
+	return func(s *settings) {
+		s.setTokens(tokens)
+	}
+}
+
+// setTokens sets the tokens, and the superscript TokenId to lexer.Exponent if they spell it or else
+// lexer.NullToken, since the lexer can't insert an operator that has no spelling.
+func (s *settings) setTokens(tokens []lexer.Token) {
+	s.tokens = tokens
+
+	s.superscript = lexer.NullToken
+	if slices.ContainsFunc(tokens, func(t lexer.Token) bool { return t.Id == lexer.Exponent }) {
+		s.superscript = lexer.Exponent
+	}
+}
+
+// WithOperations replaces the default Operations for the mode matching T.
+func WithOperations[T parser.Number](operations []parser.Operation[T]) Option {
+	return func(s *settings) {
+		switch ops := any(operations).(type) {
+		case []parser.Operation[int]:
+			s.intOperations = ops
+		case []parser.Operation[float64]:
+			s.floatOperations = ops
+		}
+	}
+}
+
+// WithFunctions replaces the default built-in functions for the mode matching T.
+func WithFunctions[T parser.Number](functions []parser.BuiltinFunction[T]) Option {
+	return func(s *settings) {
+		switch fns := any(functions).(type) {
+		case []parser.BuiltinFunction[int]:
+			s.intFunctions = fns
+		case []parser.BuiltinFunction[float64]:
+			s.floatFunctions = fns
+		}
+	}
+}
+
+// WithConstants adds named constants, such as VAT_RATE, to the built-in language.MathConstants.  Constants
+// can't be assigned to, and take priority over variables of the same name in the Environment.
+func WithConstants(constants map[string]float64) Option {
+	return func(s *settings) {
+		s.addConstants(constants)
+	}
+}
+
+// addConstants adds constants to those in s, replacing any of the same name.
+func (s *settings) addConstants(constants map[string]float64) {
+	s.constants = maps.Clone(s.constants)
+	if s.constants == nil {
+		s.constants = map[string]float64{}
+	}
+
+	maps.Copy(s.constants, constants)
+}
+
+// WithLanguage replaces the default tokens, Operations and built-in functions for both modes,
+// OperationGroups and implicit multiplication setting, which are those of language.Default, with those of
+// l, and adds l's constants to any given by WithConstants.  Superscript digits are read as an exponent if l
+// spells lexer.Exponent, and are invalid otherwise.
+func WithLanguage(l *language.Language) Option {
+	return func(s *settings) {
+		s.setTokens(l.Tokens)
+		s.addConstants(l.Constants)
+		s.opGroups = l.OperationGroups
+		s.implicitMultiply = l.ImplicitMultiplication
+		s.intOperations, s.err = language.Operations[int](*l)
+		if s.err == nil {
+			s.floatOperations, s.err = language.Operations[float64](*l)
+		}
+		if s.err == nil {
+			s.intFunctions, s.err = language.Functions[int](*l)
+		}
+		if s.err == nil {
+			s.floatFunctions, s.err = language.Functions[float64](*l)
+		}
+	}
+}
+
+// WithDialect uses the named preset language.  See language.Dialect.
+func WithDialect(name string) Option {
+	return func(s *settings) {
+		l, err := language.Dialect(name)
+		if err != nil {
+			s.err = err
+			return
+		}
+
+		WithLanguage(l)(s)
+	}
+}
+
+// WithOperationGroups replaces the default OperationGroups, which define precedence and associativity.
+func WithOperationGroups(opGroups []parser.OperationGroup) Option {
+	return func(s *settings) {
+		s.opGroups = opGroups
+	}
+}
+
+// WithImplicitMultiplication enables implicit multiplication.  See parser.WithImplicitMultiplication.
+func WithImplicitMultiplication(tok lexer.TokenId) Option {
+	return func(s *settings) {
+		s.implicitMultiply = tok
+	}
+}
+
+// WithAliases replaces the default aliases, lexer.DefaultAliases, which let typeset operators such as ×
+// stand for ASCII ones.  Passing nil disables aliases.  See lexer.WithAliases.
+func WithAliases(aliases map[string]string) Option {
+	return func(s *settings) {
+		s.aliases = aliases
+	}
+}
+
+// WithSuperscripts sets the TokenId inserted before superscript digits, which is lexer.Exponent by default,
+// so that 2³ is 8, unless the language has no spelling for it.  tok must be spelled by the tokens.  Passing
+// lexer.NullToken disables superscripts.  See lexer.WithSuperscripts.
+func WithSuperscripts(tok lexer.TokenId) Option {
+	return func(s *settings) {
+		s.superscript = tok
+	}
+}
+
+// WithComments replaces the default comment syntax, lexer.DefaultComments.  Passing the zero
+// lexer.Comments disables comments.  See lexer.WithComments.
+func WithComments(c lexer.Comments) Option {
+	return func(s *settings) {
+		s.comments = c
+	}
+}
+
+// WithMaxCallDepth limits how deeply calls to user-defined functions may be nested.
+// See parser.WithMaxCallDepth.
+func WithMaxCallDepth(n int) Option {
+	return func(s *settings) {
+		s.maxCallDepth = n
+	}
+}
+
+// Limits bounds the work done by Evaluate, for expressions from untrusted sources.  A zero field means no
+// limit, except that MaxDepth defaults to parser.DefaultMaxDepth.  Exceeding a limit gives an error
+// wrapping parser.ErrLimitExceeded.  See parser.Limits.
+type Limits struct {
+	// MaxInputLength limits the length of the expression in bytes.
+	MaxInputLength int
+	MaxTokens      int
+	MaxDepth       int
+	MaxOperations  int
+	MaxMagnitude   float64
+}
+
+// WithLimits sets the Limits that Evaluate checks.
+func WithLimits(l Limits) Option {
+	return func(s *settings) {
+		s.limits = l
+	}
+}
+
+// WithEnvironment makes the Values in env available to the expression by name.
+func WithEnvironment(env *Environment) Option {
+	return func(s *settings) {
+		s.environment = env
+	}
+}
+
+// Evaluate evaluates expr and returns its Value.  Nothing is written to standard output.  expr may be a
+// script of statements separated by semicolons, such as "rate = 0.07; net = 120; net * (1 + rate)", in which
+// case the Value of the last statement is returned.  If the script succeeds, its assignments are stored in the
+// Environment given by WithEnvironment.
+func Evaluate(expr string, opts ...Option) (Value, error) {
+	return EvaluateContext(context.Background(), expr, opts...)
+}
+
+// EvaluateContext is Evaluate with a Context, which stops the evaluation with ctx.Err() if it is cancelled
+// or its deadline passes.
+func EvaluateContext(ctx context.Context, expr string, opts ...Option) (Value, error) {
+	s, err := newSettings(opts)
+	if err != nil {
+		return Value{}, err
+	}
+
+	if err := s.checkLength(len(expr)); err != nil {
+		return Value{}, err
+	}
+
+	elements, err := s.lex(expr)
+	if err != nil {
+		return Value{}, err
+	}
+
+	return s.eval(ctx, program{elements: elements})
+}
+
+// checkLength returns an error if an input of n bytes is longer than the Limits in s allow.
+func (s settings) checkLength(n int) error {
+	if s.limits.MaxInputLength > 0 && n > s.limits.MaxInputLength {
+		return fmt.Errorf("%w: %w: input is %d bytes, more than %d",
+			parser.ErrEvaluation, parser.ErrLimitExceeded, n, s.limits.MaxInputLength)
+	}
+
+	return nil
+}
+
+// program is what eval evaluates: the elements of a script or, if node isn't nil, a compiled expression.
+type program struct {
+	elements lexer.ElementList
+	node     *parser.Node
+}
+
+// eval evaluates prog in the mode selected by s.
+func (s settings) eval(ctx context.Context, prog program) (Value, error) {
+	switch s.mode {
+	case IntegerMode:
+		return eval(ctx, prog, s.intOperations, s.intFunctions, s)
+	case FloatMode:
+		return eval(ctx, prog, s.floatOperations, s.floatFunctions, s)
+	default:
+		return Value{}, fmt.Errorf("%w: %d", errInvalidMode, s.mode)
+	}
+}
+
+// newSettings returns the default settings modified by opts.
+func newSettings(opts []Option) (settings, error) {
+	s := settings{
+		mode:     IntegerMode,
+		aliases:  lexer.DefaultAliases,
+		comments: lexer.DefaultComments,
+	}
+
+	for _, opt := range append([]Option{WithLanguage(language.Default())}, opts...) {
+		opt(&s)
+	}
+
+	return s, s.err
+}
+
+// lex converts expr to elements with the lexer options in s and opts.
+func (s settings) lex(expr string, opts ...lexer.Option) (lexer.ElementList, error) {
+	lex := lexer.NewLexer(expr, s.tokens, append([]lexer.Option{
+		lexer.WithIdentifiers(),
+		lexer.WithAliases(s.aliases),
+		lexer.WithSuperscripts(s.superscript),
+		lexer.WithComments(s.comments),
+		lexer.WithSpans(),
+	}, opts...)...)
+
+	return lex.GetElementList()
+}
+
+func eval[T parser.Number](ctx context.Context, prog program, operations []parser.Operation[T],
+	functions []parser.BuiltinFunction[T], s settings,
+) (Value, error) {
+	penv := toParser[T](s.environment)
+
+	constants, err := language.Constants[T](language.Language{Constants: s.constants})
+	if err != nil {
+		return Value{}, err
+	}
+
+	opts := []parser.Option{
+		parser.WithImplicitMultiplication(s.implicitMultiply),
+		parser.WithEnvironment(penv),
+		parser.WithMaxCallDepth(s.maxCallDepth),
+		parser.WithBuiltins(functions),
+		parser.WithConstants(constants),
+		parser.WithLimits(parser.Limits{
+			MaxTokens:     s.limits.MaxTokens,
+			MaxDepth:      s.limits.MaxDepth,
+			MaxOperations: s.limits.MaxOperations,
+			MaxMagnitude:  s.limits.MaxMagnitude,
+		}),
+	}
+
+	var trace parser.Trace[T]
+	if s.trace != nil {
+		opts = append(opts, parser.WithTrace(&trace))
+	}
+
+	if s.observer != nil {
+		opts = append(opts, parser.WithObserver[T](observer[T]{s.observer}))
+	}
+
+	p, err := parser.NewParser(operations, s.opGroups, opts...)
+	if err != nil {
+		return Value{}, err
+	}
+
+	var result *T
+	if prog.node != nil {
+		result, err = p.EvalNodeContext(ctx, *prog.node)
+	} else {
+		result, err = p.EvalStatementsContext(ctx, prog.elements)
+	}
+
+	appendSteps(s.trace, &trace)
+
+	if err != nil {
+		return Value{}, err
+	}
+
+	fromParser(s.environment, penv)
+
+	if result == nil {
+		return Value{}, ErrNoValue
+	}
+
+	return valueOf(*result), nil
+}
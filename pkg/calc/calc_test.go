@@ -0,0 +1,541 @@
+package calc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/lexer"
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/parser"
+)
+
+func TestEvaluate(t *testing.T) {
+	env := NewEnvironment()
+	env.Set("half", Float(0.5))
+	env.Set("ten", Int(10))
+
+	tests := []struct {
+		name    string
+		expr    string
+		opts    []Option
+		want    string
+		wantErr bool
+	}{
+		{name: "defaults", expr: "2+3*4", want: "14"},
+		{name: "integer division", expr: "7/2", want: "3"},
+		{name: "float mode", expr: "7/2", opts: []Option{WithMode(FloatMode)}, want: "3.5"},
+		{name: "percent truncated in integer mode", expr: "20% + 250% + 20*50%", want: "2"},
+		{name: "percent in float mode", expr: "20% + 250%", opts: []Option{WithMode(FloatMode)}, want: "2.7"},
+		{name: "environment in float mode", expr: "ten*half", opts: []Option{WithMode(FloatMode), WithEnvironment(env)}, want: "5"},
+		{name: "environment truncated in integer mode", expr: "ten+half", opts: []Option{WithEnvironment(env)}, want: "10"},
+		{name: "implicit multiplication", expr: "2(ten)", opts: []Option{WithEnvironment(env), WithImplicitMultiplication(lexer.Multiply)}, want: "20"},
+		{name: "error - undefined variable", expr: "ten", wantErr: true},
+		{name: "error - lexer", expr: "1 $ 2", wantErr: true},
+		{name: "error - decimal in integer mode", expr: "1.5", wantErr: true},
+		{name: "error - exponent in integer mode", expr: "1e6", wantErr: true},
+		{name: "exponent in float mode", expr: "1e6 + 2.5E-1", opts: []Option{WithMode(FloatMode)}, want: "1000000.25"},
+		{name: "error - invalid mode", expr: "1", opts: []Option{WithMode(Mode(7))}, wantErr: true},
+		{name: "dialect", expr: "-2**2 + 7//2", opts: []Option{WithDialect("python")}, want: "-1"},
+		{name: "dialect with implicit multiplication", expr: "2(3+4)", opts: []Option{WithDialect("textbook")}, want: "14"},
+		{name: "unicode operators and whitespace", expr: "2\u00a0×\t3 − 4 ÷ 2", want: "4"},
+		{name: "superscripts", expr: "2³ + 10²", want: "108"},
+		{name: "error - aliases disabled", expr: "2 × 3", opts: []Option{WithAliases(nil)}, wantErr: true},
+		{name: "error - superscripts disabled", expr: "2³", opts: []Option{WithSuperscripts(lexer.NullToken)}, wantErr: true},
+		{name: "error - superscripts in a language without ^", expr: "2³", opts: []Option{WithDialect("c")}, wantErr: true},
+		{name: "error - superscript operator not in the language", expr: "2", opts: []Option{WithDialect("c"), WithSuperscripts(lexer.Exponent)}, wantErr: true},
+		{name: "comments", expr: "2 * /* rate */ 3 # total", want: "6"},
+		{name: "comment marker that is a token", expr: "7 // 2 # floor division", opts: []Option{WithDialect("python")}, want: "3"},
+		{name: "error - comments disabled", expr: "1 # one", opts: []Option{WithComments(lexer.Comments{})}, wantErr: true},
+		{name: "script", expr: "rate = 0.07; net = 120; net * (1 + rate)", opts: []Option{WithMode(FloatMode)}, want: "128.4"},
+		{name: "script ending with an assignment", expr: "x = 2; y = x^3;", want: "8"},
+		{name: "error - assignment to a number", expr: "2 = 3", wantErr: true},
+		{name: "user-defined function", expr: "f(x, y) = x^2 + y; f(3, 4)", want: "13"},
+		{name: "error - runaway recursion", expr: "f(x) = f(x); f(1)", opts: []Option{WithMaxCallDepth(10)}, wantErr: true},
+		{name: "error - only definitions", expr: "f(x) = x", wantErr: true},
+		{name: "math functions", expr: "sqrt(16) + max(1, 5, 3)", want: "9"},
+		{name: "math functions in float mode", expr: "round(log(2, 10), 3)", opts: []Option{WithMode(FloatMode)}, want: "3.322"},
+		{name: "math functions in a dialect", expr: "-abs(-3)", opts: []Option{WithDialect("c")}, want: "-3"},
+		{name: "error - math domain", expr: "ln(0)", opts: []Option{WithMode(FloatMode)}, wantErr: true},
+		{name: "error - math arity", expr: "hypot(3)", wantErr: true},
+		{name: "constant", expr: "2 * pi", opts: []Option{WithMode(FloatMode)}, want: "6.283185307179586"},
+		{name: "constant in integer mode", expr: "e", want: "2"},
+		{name: "user constant", expr: "100 * (1 + VAT_RATE)", opts: []Option{WithMode(FloatMode), WithConstants(map[string]float64{"VAT_RATE": 0.2})}, want: "120"},
+		{name: "error - assignment to constant", expr: "pi = 3", wantErr: true},
+		{name: "error - function named after a constant", expr: "pi(x) = x; pi(5)", wantErr: true},
+		{name: "user constant with a dialect", expr: "100 * (1 + VAT_RATE)",
+			opts: []Option{WithMode(FloatMode), WithConstants(map[string]float64{"VAT_RATE": 0.2}), WithDialect("python")}, want: "120"},
+		{name: "error - redefined math constant", expr: "1", opts: []Option{WithConstants(map[string]float64{"tau": 6})}, wantErr: true},
+		{name: "error - unknown dialect", expr: "1", opts: []Option{WithDialect("cobol")}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Evaluate(tt.expr, tt.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Evaluate() err=%v wantErr=%v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.String() != tt.want {
+				t.Fatalf("Evaluate() got=%v want=%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluate_CustomOperators(t *testing.T) {
+	// A language with a single operator, spelled "&".
+	tokens := []lexer.Token{{Id: lexer.Plus, Value: "&"}}
+	opGroups := []parser.OperationGroup{
+		{Tokens: []lexer.TokenId{lexer.Plus}, Precedence: parser.PrecedencePlusMinus, Associativity: parser.LeftAssociative},
+	}
+	intOps := []parser.Operation[int]{
+		{Description: "Concatenate", TokenId: lexer.Plus, Fn: func(a, b int) (int, error) {
+			var n int
+			_, err := fmt.Sscan(fmt.Sprintf("%d%d", a, b), &n)
+			return n, err
+		}},
+	}
+
+	got, err := Evaluate("12 & 34 & 5", WithTokens(tokens), WithOperationGroups(opGroups), WithOperations(intOps))
+	if err != nil || got.Int() != 12345 {
+		t.Fatalf("Evaluate() = (%v,%v), want 12345", got, err)
+	}
+
+	// Only the integer operations were replaced, so float mode still adds.
+	got, err = Evaluate("12 & 34", WithMode(FloatMode), WithTokens(tokens), WithOperationGroups(opGroups), WithOperations(intOps))
+	if err != nil || got.Float() != 46 {
+		t.Fatalf("Evaluate() = (%v,%v), want 46", got, err)
+	}
+}
+
+func TestValue(t *testing.T) {
+	v := Float(-2.75)
+	if v.Mode() != FloatMode || v.Int() != -2 || v.Float() != -2.75 || v.String() != "-2.75" {
+		t.Fatalf("Float(-2.75) = %#v", v)
+	}
+
+	i := v.In(IntegerMode)
+	if i.Mode() != IntegerMode || i.String() != "-2" {
+		t.Fatalf("In(IntegerMode) = %#v", i)
+	}
+
+	if s := Int(7).In(FloatMode).String(); s != "7" {
+		t.Fatalf("Int(7).In(FloatMode) = %q", s)
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	for name, want := range modeNames {
+		got, err := ParseMode(name)
+		if err != nil || got != want || got.String() != name {
+			t.Fatalf("ParseMode(%q) = (%v,%v), want %v", name, got, err, want)
+		}
+	}
+
+	if _, err := ParseMode("complex"); err == nil {
+		t.Fatalf("ParseMode(%q) expected an error", "complex")
+	}
+}
+
+func TestEvaluate_AssignmentsAreStored(t *testing.T) {
+	env := NewEnvironment()
+	env.Set("half", Float(0.5))
+
+	if _, err := Evaluate("x = 6; y = x * 7; half", WithEnvironment(env)); err != nil {
+		t.Fatalf("Evaluate() err=%v", err)
+	}
+
+	if y, ok := env.Get("y"); !ok || y.String() != "42" {
+		t.Fatalf("y = %v, %v; want 42", y, ok)
+	}
+
+	// half was only read, so it keeps its float value despite being truncated in integer mode.
+	if half, _ := env.Get("half"); half.String() != "0.5" {
+		t.Fatalf("half = %v, want 0.5", half)
+	}
+
+	// A failing script stores nothing.
+	if _, err := Evaluate("z = 1; 1/0", WithEnvironment(env)); err == nil {
+		t.Fatalf("Evaluate() err=nil, want division by zero")
+	}
+
+	if _, ok := env.Get("z"); ok {
+		t.Fatalf("z was stored by a failing script")
+	}
+}
+
+func TestEvaluate_FunctionsAreStored(t *testing.T) {
+	env := NewEnvironment()
+
+	if _, err := Evaluate("double(x) = 2*x", WithEnvironment(env)); !errors.Is(err, ErrNoValue) {
+		t.Fatalf("Evaluate() err=%v, want ErrNoValue", err)
+	}
+
+	got, err := Evaluate("double(21)", WithEnvironment(env), WithMode(FloatMode))
+	if err != nil || got.String() != "42" {
+		t.Fatalf("Evaluate() = (%v, %v), want 42", got, err)
+	}
+}
+
+func TestEvaluate_Trace(t *testing.T) {
+	var trace Trace
+
+	got, err := Evaluate("x = 2; x^2 + max(1, 3)", WithTrace(&trace))
+	if err != nil || got.String() != "7" {
+		t.Fatalf("Evaluate() = (%v, %v), want 7", got, err)
+	}
+
+	want := []string{"max(1, 3) = 3", "2^2 = 4", "4+3 = 7"}
+	if len(trace.Steps) != len(want) {
+		t.Fatalf("Trace has %d steps, want %d: %v", len(trace.Steps), len(want), trace.Steps)
+	}
+
+	for i, step := range trace.Steps {
+		if step.String() != want[i] {
+			t.Fatalf("Steps[%d] = %q, want %q", i, step, want[i])
+		}
+	}
+
+	// The last step covers "x^2 + max(1, 3)", which starts after the assignment.
+	if span := trace.Steps[2].Span; span != (lexer.Span{Start: 7, End: 22}) {
+		t.Fatalf("Steps[2].Span = %+v, want {7 22}", span)
+	}
+}
+
+// counter is an Observer that counts how often each operator is used.
+type counter map[string]int
+
+func (c counter) BeforeOperation(e OperationEvent) {}
+
+func (c counter) AfterOperation(e OperationEvent) {
+	c[e.Description]++
+}
+
+func TestEvaluate_Observer(t *testing.T) {
+	c := counter{}
+
+	for _, mode := range []Mode{IntegerMode, FloatMode} {
+		if _, err := Evaluate("1 + 2*3 - 4*5", WithMode(mode), WithObserver(c)); err != nil {
+			t.Fatalf("Evaluate() err=%v", err)
+		}
+	}
+
+	want := counter{"Plus": 2, "Minus": 2, "Multiply": 4}
+	if !reflect.DeepEqual(c, want) {
+		t.Fatalf("counts = %v, want %v", c, want)
+	}
+}
+
+func TestEvaluateContext_Limits(t *testing.T) {
+	expired, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		expr    string
+		limits  Limits
+		wantErr error
+	}{
+		{name: "within limits", ctx: context.Background(), expr: "2 * (3 + 4)", limits: Limits{MaxInputLength: 11, MaxTokens: 7, MaxDepth: 1, MaxOperations: 2, MaxMagnitude: 14}},
+		{name: "input too long", ctx: context.Background(), expr: "1 + 2", limits: Limits{MaxInputLength: 4}, wantErr: parser.ErrLimitExceeded},
+		{name: "too many tokens", ctx: context.Background(), expr: "1+2+3", limits: Limits{MaxTokens: 3}, wantErr: parser.ErrLimitExceeded},
+		{name: "nested too deeply", ctx: context.Background(), expr: "((1))", limits: Limits{MaxDepth: 1}, wantErr: parser.ErrLimitExceeded},
+		{name: "too many operations", ctx: context.Background(), expr: "sqrt(1) + 1", limits: Limits{MaxOperations: 1}, wantErr: parser.ErrLimitExceeded},
+		{name: "too large", ctx: context.Background(), expr: "3^30", limits: Limits{MaxMagnitude: math.MaxInt32}, wantErr: parser.ErrLimitExceeded},
+		// 2^32 * 2^32 wraps around to exactly 0 as an int, so the overflow must be caught before the magnitude is checked.
+		{name: "overflow wraps to zero", ctx: context.Background(), expr: "4294967296*4294967296", limits: Limits{MaxMagnitude: 1e15}, wantErr: parser.ErrEvaluation},
+		{name: "overflow", ctx: context.Background(), expr: "9^9^9^9", wantErr: parser.ErrEvaluation},
+		{name: "deadline", ctx: expired, expr: "1 + 2", wantErr: context.DeadlineExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := EvaluateContext(tt.ctx, tt.expr, WithLimits(tt.limits))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("EvaluateContext() err=%v wantErr=%v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		opts    []Option
+		want    string
+		wantErr bool
+	}{
+		{name: "redundant parentheses", expr: "((1+2))*3", want: "(1 + 2) * 3"},
+		{name: "right associative", expr: "2^(3^2)", want: "2 ^ 3 ^ 2"},
+		{name: "aliases and superscripts", expr: "2²×3", want: "2 ^ 2 * 3"},
+		{name: "script", expr: "f(x)=x*(x)\n;y=f((2))", want: "f(x) = x * x; y = f(2)"},
+		{name: "comments", expr: "# area\nf(x)=x*(x) # square\n;y=f((2)) /* two */ +1 // y", want: "# area\nf(x) = x * x; # square\ny = f(2) /* two */ + 1 // y"},
+		{name: "comment of a statement", expr: "x = 1; # x\n; x", want: "x = 1; # x\nx"},
+		{name: "dialect", expr: "(-2)**2 + -(2**2)", opts: []Option{WithDialect("python")}, want: "(-2) ** 2 + -2 ** 2"},
+		{name: "implicit multiplication", expr: "2(x+1)", opts: []Option{WithImplicitMultiplication(lexer.ImplicitMultiply)}, want: "2(x + 1)"},
+		{name: "error - unbalanced", expr: "(1+2", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Canonical(tt.expr, tt.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Canonical() err=%v wantErr=%v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Fatalf("Canonical() got=%q want=%q", got, tt.want)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			// The canonical form, comments included, is its own canonical form.
+			if again, err := Canonical(got, tt.opts...); err != nil || again != got {
+				t.Fatalf("Canonical(%q) = (%q, %v)", got, again, err)
+			}
+		})
+	}
+}
+
+func TestDefinition(t *testing.T) {
+	env := NewEnvironment()
+
+	// The definitions are stored by evaluating them, as the REPL does.
+	opts := []Option{WithEnvironment(env), WithImplicitMultiplication(lexer.ImplicitMultiply)}
+	if _, err := Evaluate("sq(x) = x²; f(x, y) = 2(x+y) - sq(x)", opts...); !errors.Is(err, ErrNoValue) {
+		t.Fatalf("Evaluate() err=%v", err)
+	}
+
+	want := map[string]string{"sq": "sq(x) = x ^ 2", "f": "f(x, y) = 2(x + y) - sq(x)"}
+
+	for name, want := range want {
+		fn, ok := env.Function(name)
+		if !ok {
+			t.Fatalf("Function(%q) not defined", name)
+		}
+
+		if got, err := Definition(name, fn, opts...); err != nil || got != want {
+			t.Fatalf("Definition(%q) = (%q, %v), want %q", name, got, err, want)
+		}
+	}
+}
+
+func TestRPN(t *testing.T) {
+	tests := []struct {
+		name       string
+		expr       string
+		opts       []Option
+		wantRPN    string
+		wantPolish string
+		// wantInfix is the result of reading either notation back.
+		wantInfix string
+		wantErr   bool
+	}{
+		{name: "precedence", expr: "(1+2)*3 - 4", wantRPN: "1 2 + 3 * 4 -", wantPolish: "- * + 1 2 3 4", wantInfix: "(1 + 2) * 3 - 4"},
+		{name: "call", expr: "max(2, 3^2)", wantRPN: "2 3 2 ^ max/2", wantPolish: "max/2 2 ^ 3 2", wantInfix: "max(2, 3 ^ 2)"},
+		{name: "dialect", expr: "-2**2", opts: []Option{WithDialect("python")},
+			wantRPN: "2 2 ** u-", wantPolish: "u- ** 2 2", wantInfix: "-2 ** 2"},
+		{name: "implicit multiplication", expr: "2(x+1)", opts: []Option{WithImplicitMultiplication(lexer.ImplicitMultiply)},
+			wantRPN: "2 x 1 + *", wantPolish: "* 2 + x 1", wantInfix: "2 * (x + 1)"},
+		{name: "error - assignment", expr: "x = 1", wantErr: true},
+		{name: "error - script", expr: "1; 2", wantErr: true},
+		{name: "error - comment", expr: "1 + 2 # three", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rpn, err := RPN(tt.expr, tt.opts...)
+			if (err != nil) != tt.wantErr || rpn != tt.wantRPN {
+				t.Fatalf("RPN() = (%q, %v), want %q", rpn, err, tt.wantRPN)
+			}
+
+			polish, err := Polish(tt.expr, tt.opts...)
+			if (err != nil) != tt.wantErr || polish != tt.wantPolish {
+				t.Fatalf("Polish() = (%q, %v), want %q", polish, err, tt.wantPolish)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if got, err := FromRPN(rpn, tt.opts...); err != nil || got != tt.wantInfix {
+				t.Fatalf("FromRPN() = (%q, %v), want %q", got, err, tt.wantInfix)
+			}
+
+			if got, err := FromPolish(polish, tt.opts...); err != nil || got != tt.wantInfix {
+				t.Fatalf("FromPolish() = (%q, %v), want %q", got, err, tt.wantInfix)
+			}
+		})
+	}
+}
+
+func TestFromRPN(t *testing.T) {
+	tests := []struct {
+		name    string
+		rpn     string
+		want    int
+		wantErr bool
+	}{
+		{name: "arithmetic", rpn: "1 2 + 3 *", want: 9},
+		{name: "right associative", rpn: "2 3 2 ^ ^", want: 512},
+		{name: "call", rpn: "1 5 3 max/3", want: 5},
+		{name: "error - missing operand", rpn: "1 +", wantErr: true},
+		{name: "error - unknown word", rpn: "1 2 plus", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := FromRPN(tt.rpn)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FromRPN() err=%v wantErr=%v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			got, err := Evaluate(expr)
+			if err != nil || got.Int() != tt.want {
+				t.Fatalf("Evaluate(%q) = (%v, %v), want %v", expr, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateRPN(t *testing.T) {
+	tests := []struct {
+		name    string
+		rpn     string
+		opts    []Option
+		want    int
+		wantErr error
+	}{
+		{name: "arithmetic", rpn: "1 2 + 3 *", want: 9},
+		{name: "negative number", rpn: "3 -5 +", want: -2},
+		{name: "negative number with a prefix minus", rpn: "-2 2 **", opts: []Option{WithDialect("python")}, want: 4},
+		{name: "error - syntax", rpn: "1 +", wantErr: parser.ErrSyntax},
+		{name: "error - evaluation", rpn: "1 0 /", wantErr: parser.ErrEvaluation},
+		{name: "error - too long", rpn: "1 2 +", opts: []Option{WithLimits(Limits{MaxInputLength: 3})}, wantErr: parser.ErrLimitExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := EvaluateRPN(tt.rpn, tt.opts...)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("EvaluateRPN() err=%v wantErr=%v", err, tt.wantErr)
+			}
+
+			if tt.wantErr == nil && got.Int() != tt.want {
+				t.Fatalf("EvaluateRPN() got=%v want=%d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		opts    []Option
+		want    Value
+		wantErr bool
+	}{
+		{name: "arithmetic", expr: "(1+2)*3 - 2^3^2", want: Int(-503)},
+		{name: "call", expr: "max(2, 7) - 4", want: Int(3)},
+		{name: "float", expr: "sqrt(2.25) + pi - pi", opts: []Option{WithMode(FloatMode)}, want: Float(1.5)},
+		{name: "dialect", expr: "-2**2", opts: []Option{WithDialect("python")}, want: Int(-4)},
+		{name: "implicit multiplication", expr: "2(3+1)", opts: []Option{WithImplicitMultiplication(lexer.ImplicitMultiply)}, want: Int(8)},
+		{name: "alias", expr: "2×3", want: Int(6)},
+		{name: "long sum", expr: strings.Repeat("1+", 5000) + "1", want: Int(5001)},
+		{name: "error - script", expr: "x = 1; x", wantErr: true},
+		{name: "error - comment", expr: "2 * pi /* tau */", wantErr: true},
+		{name: "error - unbalanced", expr: "(1+2", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := Compile(tt.expr, tt.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Compile() err=%v wantErr=%v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			got, err := EvaluateCompiled(data, tt.opts...)
+			if err != nil || got != tt.want {
+				t.Fatalf("EvaluateCompiled(%s) = (%v, %v), want %v", data, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompile_BindsConstants(t *testing.T) {
+	constants := WithConstants(map[string]float64{"VAT": 0.2, "OFFSET": -1})
+
+	for _, mode := range []Mode{IntegerMode, FloatMode} {
+		expr := "100 * pi + VAT - OFFSET"
+
+		data, err := Compile(expr, constants, WithMode(mode))
+		if err != nil {
+			t.Fatalf("Compile() err=%v", err)
+		}
+
+		if strings.Contains(string(data), "identifier") {
+			t.Fatalf("Compile() = %s, want the constants replaced by numbers", data)
+		}
+
+		want, err := Evaluate(expr, constants, WithMode(mode))
+		if err != nil {
+			t.Fatalf("Evaluate() err=%v", err)
+		}
+
+		// VAT and OFFSET aren't known here, so their values must have been compiled in.
+		if got, err := EvaluateCompiled(data, WithMode(mode)); err != nil || got != want {
+			t.Fatalf("EvaluateCompiled(%s) = (%v, %v), want %v", data, got, err, want)
+		}
+	}
+}
+
+func TestEvaluateCompiledErrors(t *testing.T) {
+	data, err := Compile("2**3", WithDialect("python"))
+	if err != nil {
+		t.Fatalf("Compile() err=%v", err)
+	}
+
+	// ** isn't an operator of the default language.
+	if _, err := EvaluateCompiled(data); err == nil {
+		t.Fatalf("EvaluateCompiled() with another language err=nil")
+	}
+
+	if _, err := EvaluateCompiled(data, WithDialect("python"), WithLimits(Limits{MaxInputLength: 10})); !errors.Is(err, parser.ErrLimitExceeded) {
+		t.Fatalf("EvaluateCompiled() err=%v want %v", err, parser.ErrLimitExceeded)
+	}
+
+	if _, err := EvaluateCompiled([]byte(`{"version":1,"nodes":[{"type":"identifier","value":"y"}]}`)); !errors.Is(err, parser.ErrEvaluation) {
+		t.Fatalf("EvaluateCompiled() err=%v want %v", err, parser.ErrEvaluation)
+	}
+
+	// The tree is evaluated with the same Limits as source text, with MaxTokens limiting its nodes.
+	data, err = Compile("1+2+3")
+	if err != nil {
+		t.Fatalf("Compile() err=%v", err)
+	}
+
+	for _, limits := range []Limits{{MaxTokens: 4}, {MaxOperations: 1}, {MaxMagnitude: 2}} {
+		if _, err := EvaluateCompiled(data, WithLimits(limits)); !errors.Is(err, parser.ErrLimitExceeded) {
+			t.Fatalf("EvaluateCompiled() with %+v err=%v want %v", limits, err, parser.ErrLimitExceeded)
+		}
+	}
+}
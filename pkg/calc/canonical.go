@@ -0,0 +1,177 @@
+package calc
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/lexer"
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/parser"
+)
+
+// Canonical returns expr rewritten in canonical form by parser.Printer, without evaluating it, so that
+// formulas stored in version control give stable diffs.  Operators are spelled with the first spelling in
+// the tokens set by opts, infix operators are surrounded by single spaces, and only the parentheses needed
+// by the OperationGroups are kept, so "((1+2))*3" becomes "(1 + 2) * 3".  The statements of a script are
+// separated by "; ".  Comments are kept next to the operand or operator they follow, and a line comment is
+// followed by a new line, so "f(x)=x*(x) # square\n;y=f((2))" becomes "f(x) = x * x; # square\ny = f(2)".
+func Canonical(expr string, opts ...Option) (string, error) {
+	s, err := newSettings(opts)
+	if err != nil {
+		return "", err
+	}
+
+	statements, nodes, err := s.parse(expr)
+	if err != nil {
+		return "", err
+	}
+
+	return s.canonical(statements, nodes)
+}
+
+// Definition returns the definition of the Function called name as Canonical writes it, such as
+// "f(x, y) = x ^ 2 + y", for listing the Functions of an Environment.
+func Definition(name string, fn parser.Function, opts ...Option) (string, error) {
+	s, err := newSettings(opts)
+	if err != nil {
+		return "", err
+	}
+
+	statements := []parser.Statement{{Name: name, Params: fn.Params, Expression: fn.Body}}
+
+	nodes, err := s.parseStatements(statements)
+	if err != nil {
+		return "", err
+	}
+
+	return s.canonical(statements, nodes)
+}
+
+// canonical writes statements, whose expressions have the trees nodes, in canonical form.  The comments
+// that lead a statement's expression are written before the statement, and those that follow it after its
+// Semicolon.
+func (s settings) canonical(statements []parser.Statement, nodes []parser.Node) (string, error) {
+	pr := parser.NewPrinter(s.tokens, s.opGroups)
+	pr.Comments = s.comments
+	assign := " " + pr.Spelling(lexer.Assign) + " "
+
+	var sb strings.Builder
+
+	// comment writes c and what separates it from the text after it.
+	comment := func(c parser.Comment) {
+		sb.WriteString(c.Text)
+
+		if pr.LineComment(c.Text) {
+			sb.WriteString("\n")
+		} else {
+			sb.WriteString(" ")
+		}
+	}
+
+	for i, st := range statements {
+		n := nodes[i]
+		comments := n.Comments
+		n.Comments = nil
+
+		text, err := pr.Format(n)
+		if err != nil {
+			return "", err
+		}
+
+		switch {
+		case st.Params != nil:
+			params := strings.Join(st.Params, pr.Spelling(lexer.Comma)+" ")
+			text = st.Name + pr.Spelling(lexer.LParen) + params + pr.Spelling(lexer.RParen) + assign + text
+		case st.Name != "":
+			text = st.Name + assign + text
+		}
+
+		for _, c := range comments {
+			if c.Leading {
+				comment(c)
+			}
+		}
+
+		sb.WriteString(text)
+
+		if i < len(statements)-1 {
+			sb.WriteString(pr.Spelling(lexer.Semicolon))
+		}
+
+		sb.WriteString(" ")
+
+		for _, c := range comments {
+			if !c.Leading {
+				comment(c)
+			}
+		}
+	}
+
+	return strings.TrimRight(sb.String(), " \n"), nil
+}
+
+// parse returns the statements of expr and the tree of each statement's expression, without evaluating them.
+// Comments are kept by the lexer, and parser.Parse attaches them to the trees.
+func (s settings) parse(expr string) ([]parser.Statement, []parser.Node, error) {
+	elements, err := s.lex(expr, lexer.WithTrivia())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	statements, err := parser.SplitStatements(elements)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	nodes, err := s.parseStatements(statements)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return statements, nodes, nil
+}
+
+// parseStatements returns the tree of each statement's expression.
+func (s settings) parseStatements(statements []parser.Statement) ([]parser.Node, error) {
+	switch s.mode {
+	case IntegerMode:
+		return parse(statements, s.intOperations, s.intFunctions, s)
+	case FloatMode:
+		return parse(statements, s.floatOperations, s.floatFunctions, s)
+	default:
+		return nil, fmt.Errorf("%w: %d", errInvalidMode, s.mode)
+	}
+}
+
+func parse[T parser.Number](statements []parser.Statement, operations []parser.Operation[T],
+	functions []parser.BuiltinFunction[T], s settings,
+) ([]parser.Node, error) {
+	// Functions must be known to tell a call such as f(2) from an implicit multiplication.
+	penv := toParser[T](s.environment)
+
+	p, err := parser.NewParser(operations, s.opGroups,
+		parser.WithImplicitMultiplication(s.implicitMultiply),
+		parser.WithEnvironment(penv),
+		parser.WithBuiltins(functions),
+		parser.WithLimits(parser.Limits{MaxDepth: s.limits.MaxDepth}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make([]parser.Node, 0, len(statements))
+
+	for _, st := range statements {
+		if st.Params != nil {
+			penv.Define(st.Name, parser.Function{Params: st.Params, Body: st.Expression})
+		}
+
+		n, err := p.Parse(st.Expression)
+		if err != nil {
+			return nil, err
+		}
+
+		nodes = append(nodes, n)
+	}
+
+	return nodes, nil
+}
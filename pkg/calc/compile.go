@@ -0,0 +1,102 @@
+package calc
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/language"
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/parser"
+)
+
+// Compile parses expr without evaluating it and returns its tree in the JSON representation described by
+// parser.MarshalNode, so that a formula can be parsed once, stored or sent to another service, and evaluated
+// there by EvaluateCompiled without its source text being parsed again.  expr must be a single expression,
+// not an assignment or a script.  Constants such as pi are replaced by their values in the mode set by opts,
+// so the compiled expression doesn't depend on the constants known where it is evaluated.
+func Compile(expr string, opts ...Option) ([]byte, error) {
+	s, err := newSettings(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := s.expression(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	switch s.mode {
+	case IntegerMode:
+		err = bindConstants[int](&n, s)
+	case FloatMode:
+		err = bindConstants[float64](&n, s)
+	default:
+		err = fmt.Errorf("%w: %d", errInvalidMode, s.mode)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return parser.MarshalNode(n, s.tokens)
+}
+
+// bindConstants replaces each IdentifierNode in n that names one of the constants in s with a NumberNode
+// for its value as a T, as the Parser would resolve it.
+func bindConstants[T parser.Number](n *parser.Node, s settings) error {
+	constants, err := language.Constants[T](language.Language{Constants: s.constants})
+	if err != nil {
+		return err
+	}
+
+	// The tree is walked without recursion, since a long expression can make it deep.
+	for stack := []*parser.Node{n}; len(stack) > 0; {
+		m := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if v, ok := constants[m.Value]; ok && m.Type == parser.IdentifierNode {
+			m.Type, m.Value = parser.NumberNode, formatNumber(v)
+		}
+
+		for i := range m.Operands {
+			stack = append(stack, &m.Operands[i])
+		}
+	}
+
+	return nil
+}
+
+// formatNumber returns the number literal for v.
+func formatNumber[T parser.Number](v T) string {
+	if f, ok := any(v).(float64); ok {
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+
+	return fmt.Sprint(v)
+}
+
+// EvaluateCompiled evaluates an expression compiled by Compile.  The data is checked by parser.UnmarshalNode
+// against the language set by opts, which should be the one it was compiled with, and the tree is evaluated
+// directly by parser.EvalNode.  MaxInputLength limits the length of data, and MaxTokens the number of nodes.
+func EvaluateCompiled(data []byte, opts ...Option) (Value, error) {
+	return EvaluateCompiledContext(context.Background(), data, opts...)
+}
+
+// EvaluateCompiledContext is EvaluateCompiled with a Context.  See EvaluateContext.
+func EvaluateCompiledContext(ctx context.Context, data []byte, opts ...Option) (Value, error) {
+	s, err := newSettings(opts)
+	if err != nil {
+		return Value{}, err
+	}
+
+	if err := s.checkLength(len(data)); err != nil {
+		return Value{}, err
+	}
+
+	n, err := parser.UnmarshalNode(data, s.tokens, s.opGroups)
+	if err != nil {
+		return Value{}, err
+	}
+
+	return s.eval(ctx, program{node: &n})
+}
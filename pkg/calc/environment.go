@@ -0,0 +1,84 @@
+package calc
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/parser"
+)
+
+func NewEnvironment() *Environment {
+	return &Environment{variables: map[string]Value{}, functions: map[string]parser.Function{}}
+}
+
+// Get returns the named Value and whether it is defined.
+func (env *Environment) Get(name string) (Value, bool) {
+	v, ok := env.variables[name]
+	return v, ok
+}
+
+// Set defines the named Value, replacing any previous value.
+func (env *Environment) Set(name string, v Value) {
+	env.variables[name] = v
+}
+
+// Names returns the names of all defined Values in sorted order.
+func (env *Environment) Names() []string {
+	return slices.Sorted(maps.Keys(env.variables))
+}
+
+// Define defines the named Function, replacing any previous definition.  Functions are usually
+// defined by scripts such as "f(x, y) = x^2 + y".
+func (env *Environment) Define(name string, fn parser.Function) {
+	env.functions[name] = fn
+}
+
+// Function returns the named Function and whether it is defined.
+func (env *Environment) Function(name string) (parser.Function, bool) {
+	fn, ok := env.functions[name]
+	return fn, ok
+}
+
+// FunctionNames returns the names of all defined Functions in sorted order.
+func (env *Environment) FunctionNames() []string {
+	return slices.Sorted(maps.Keys(env.functions))
+}
+
+// toParser copies env into a new parser.Environment for evaluation in mode T.  A nil env gives an
+// empty parser.Environment.
+func toParser[T parser.Number](env *Environment) *parser.Environment[T] {
+	penv := parser.NewEnvironment[T]()
+	if env == nil {
+		return penv
+	}
+
+	for name, v := range env.variables {
+		penv.Set(name, as[T](v))
+	}
+
+	for name, fn := range env.functions {
+		penv.Define(name, fn)
+	}
+
+	return penv
+}
+
+// fromParser stores the variables and Functions in penv that were assigned or defined during evaluation in
+// env, which may be nil.  Variables whose values are unchanged keep their original mode.
+func fromParser[T parser.Number](env *Environment, penv *parser.Environment[T]) {
+	if env == nil {
+		return
+	}
+
+	for _, name := range penv.Names() {
+		v, _ := penv.Get(name)
+		if old, ok := env.Get(name); !ok || as[T](old) != v {
+			env.Set(name, valueOf(v))
+		}
+	}
+
+	for _, name := range penv.FunctionNames() {
+		fn, _ := penv.Function(name)
+		env.Define(name, fn)
+	}
+}
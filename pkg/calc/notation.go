@@ -0,0 +1,113 @@
+package calc
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/lexer"
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/parser"
+)
+
+var (
+	errNotExpression = fmt.Errorf("%w: not a single expression", parser.ErrSyntax)
+	errComment       = fmt.Errorf("%w: comments can't be written in this notation and would be lost", parser.ErrSyntax)
+)
+
+// RPN returns expr in Reverse Polish notation, such as "1 2 + 3 *" for "(1+2)*3", without evaluating it.
+// Operators are spelled with the first spelling in the tokens set by opts and grouped by its
+// OperationGroups.  expr must be a single expression, not an assignment or a script.  See parser.Printer.RPN.
+func RPN(expr string, opts ...Option) (string, error) {
+	return notation(expr, opts, parser.Printer.RPN)
+}
+
+// Polish returns expr in Polish notation, such as "* + 1 2 3" for "(1+2)*3".  See RPN.
+func Polish(expr string, opts ...Option) (string, error) {
+	return notation(expr, opts, parser.Printer.Polish)
+}
+
+func notation(expr string, opts []Option, write func(parser.Printer, parser.Node) (string, error)) (string, error) {
+	s, err := newSettings(opts)
+	if err != nil {
+		return "", err
+	}
+
+	n, err := s.expression(expr)
+	if err != nil {
+		return "", err
+	}
+
+	return write(parser.NewPrinter(s.tokens, s.opGroups), n)
+}
+
+// expression returns the tree of expr, which must be a single expression rather than an assignment or a script.
+// RPN, Polish notation and JSON have no comments, so expr mustn't have any.
+func (s settings) expression(expr string) (parser.Node, error) {
+	statements, nodes, err := s.parse(expr)
+	if err != nil {
+		return parser.Node{}, err
+	}
+
+	if len(statements) != 1 || statements[0].Name != "" {
+		return parser.Node{}, fmt.Errorf("%w: %q", errNotExpression, expr)
+	}
+
+	if slices.ContainsFunc(statements[0].Expression, func(el lexer.Element) bool { return el.Token == lexer.Comment }) {
+		return parser.Node{}, errComment
+	}
+
+	return nodes[0], nil
+}
+
+// FromRPN converts rpn from Reverse Polish notation, as written by RPN with the same opts, to an infix
+// expression in canonical form, which Evaluate accepts.  See Canonical and parser.ParseRPN.
+func FromRPN(rpn string, opts ...Option) (string, error) {
+	return fromNotation(rpn, opts, parser.ParseRPN)
+}
+
+// EvaluateRPN evaluates rpn, in Reverse Polish notation as written by RPN with the same opts, without turning
+// it into an infix expression first, so that a negative number such as -5 can be used even in a language
+// without a prefix minus.  See parser.ParseRPN and EvaluateCompiled.
+func EvaluateRPN(rpn string, opts ...Option) (Value, error) {
+	return EvaluateRPNContext(context.Background(), rpn, opts...)
+}
+
+// EvaluateRPNContext is EvaluateRPN with a Context.  See EvaluateContext.
+func EvaluateRPNContext(ctx context.Context, rpn string, opts ...Option) (Value, error) {
+	s, err := newSettings(opts)
+	if err != nil {
+		return Value{}, err
+	}
+
+	if err := s.checkLength(len(rpn)); err != nil {
+		return Value{}, err
+	}
+
+	n, err := parser.ParseRPN(rpn, s.tokens, s.opGroups)
+	if err != nil {
+		return Value{}, err
+	}
+
+	return s.eval(ctx, program{node: &n})
+}
+
+// FromPolish converts polish from Polish notation, as written by Polish, to an infix expression.  See FromRPN.
+func FromPolish(polish string, opts ...Option) (string, error) {
+	return fromNotation(polish, opts, parser.ParsePolish)
+}
+
+func fromNotation(text string, opts []Option,
+	read func(string, []lexer.Token, []parser.OperationGroup) (parser.Node, error),
+) (string, error) {
+	s, err := newSettings(opts)
+	if err != nil {
+		return "", err
+	}
+
+	n, err := read(text, s.tokens, s.opGroups)
+	if err != nil {
+		return "", err
+	}
+
+	return parser.NewPrinter(s.tokens, s.opGroups).Format(n)
+}
@@ -0,0 +1,56 @@
+package calc
+
+import (
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/lexer"
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/parser"
+)
+
+// Observer is notified before and after each operator is applied.  See parser.Observer.
+type Observer interface {
+	BeforeOperation(e OperationEvent)
+	AfterOperation(e OperationEvent)
+}
+
+// OperationEvent describes an operator being applied.  See parser.OperationEvent.
+type OperationEvent struct {
+	Description string
+	TokenId     lexer.TokenId
+	Operands    []Value
+	Result      Value
+	Err         error
+}
+
+// WithObserver notifies o of every operator applied, in either Mode.
+func WithObserver(o Observer) Option {
+	return func(s *settings) {
+		s.observer = o
+	}
+}
+
+// observer adapts an Observer to a parser.Observer[T].
+type observer[T parser.Number] struct {
+	o Observer
+}
+
+func (a observer[T]) BeforeOperation(e parser.OperationEvent[T]) {
+	a.o.BeforeOperation(eventOf(e))
+}
+
+func (a observer[T]) AfterOperation(e parser.OperationEvent[T]) {
+	a.o.AfterOperation(eventOf(e))
+}
+
+func eventOf[T parser.Number](e parser.OperationEvent[T]) OperationEvent {
+	operands := make([]Value, len(e.Operands))
+	for i, operand := range e.Operands {
+		operands[i] = valueOf(operand)
+	}
+
+	return OperationEvent{
+		Description: e.Description,
+		TokenId:     e.TokenId,
+		Operands:    operands,
+		Result:      valueOf(e.Result),
+		Err:         e.Err,
+	}
+}
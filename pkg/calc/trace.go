@@ -0,0 +1,55 @@
+package calc
+
+import (
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/lexer"
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/parser"
+)
+
+// Trace records how Evaluate reached its result.  See WithTrace.
+type Trace struct {
+	Steps []Step
+}
+
+// Step is a single reduction, such as 3*4 = 12.  See parser.Step.  Span is a range of byte offsets
+// in the expression given to Evaluate.
+type Step struct {
+	Description string
+	Expression  string
+	Operands    []Value
+	Result      Value
+	Span        lexer.Span
+}
+
+func (s Step) String() string {
+	return s.Expression + " = " + s.Result.String()
+}
+
+// WithTrace makes Evaluate append a Step to trace for each operator applied and function called, in the
+// order they happen.  If evaluation fails, the Steps made before the failure are kept.
+func WithTrace(trace *Trace) Option {
+	return func(s *settings) {
+		s.trace = trace
+	}
+}
+
+// appendSteps converts the Steps of from and appends them to to, if it isn't nil.
+func appendSteps[T parser.Number](to *Trace, from *parser.Trace[T]) {
+	if to == nil {
+		return
+	}
+
+	for _, step := range from.Steps {
+		operands := make([]Value, len(step.Operands))
+		for i, operand := range step.Operands {
+			operands[i] = valueOf(operand)
+		}
+
+		to.Steps = append(to.Steps, Step{
+			Description: step.Description,
+			Expression:  step.Expression,
+			Operands:    operands,
+			Result:      valueOf(step.Result),
+			Span:        step.Span,
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package calc
+
+import (
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/lexer"
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/parser"
+)
+
+// Mode selects the numeric type that expressions are evaluated with.
+type Mode int8
+
+const (
+	IntegerMode Mode = iota // IntegerMode evaluates with parser.Parser[int], so 7/2 is 3
+	FloatMode               // FloatMode evaluates with parser.Parser[float64], so 7/2 is 3.5
+)
+
+// Value is the result of an evaluation.  It remembers the Mode it was produced in.
+type Value struct {
+	mode Mode
+	i    int
+	f    float64
+}
+
+// Environment holds named Values that expressions can refer to.  Values are converted to the
+// evaluation Mode when they are used, so an Environment can be shared between modes.
+type Environment struct {
+	variables map[string]Value
+	functions map[string]parser.Function
+}
+
+// Option configures a call to Evaluate.
+type Option func(*settings)
+
+// settings holds the configuration built up by Options.  Operations are held for
+// both modes because Options may be given in any order.
+type settings struct {
+	mode             Mode
+	tokens           []lexer.Token
+	intOperations    []parser.Operation[int]
+	floatOperations  []parser.Operation[float64]
+	intFunctions     []parser.BuiltinFunction[int]
+	floatFunctions   []parser.BuiltinFunction[float64]
+	opGroups         []parser.OperationGroup
+	implicitMultiply lexer.TokenId
+	aliases          map[string]string
+	superscript      lexer.TokenId
+	comments         lexer.Comments
+	environment      *Environment
+	maxCallDepth     int
+	constants        map[string]float64
+	trace            *Trace
+	observer         Observer
+	limits           Limits
+	// err records a failure while applying an Option, to be returned by Evaluate.
+	err error
+}
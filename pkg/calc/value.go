@@ -0,0 +1,87 @@
+package calc
+
+import (
+	"fmt"
+
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/format"
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/parser"
+)
+
+// Int returns an IntegerMode Value.
+func Int(v int) Value {
+	return Value{mode: IntegerMode, i: v, f: float64(v)}
+}
+
+// Float returns a FloatMode Value.
+func Float(v float64) Value {
+	return Value{mode: FloatMode, i: int(v), f: v}
+}
+
+// valueOf wraps the result of a Parser[T] in a Value.
+func valueOf[T parser.Number](v T) Value {
+	switch v := any(v).(type) {
+	case int:
+		return Int(v)
+	case float64:
+		return Float(v)
+	default:
+		panic("unreachable")
+	}
+}
+
+// as converts v to T, truncating if a FloatMode Value is converted to int.
+func as[T parser.Number](v Value) T {
+	var t T
+
+	switch p := any(&t).(type) {
+	case *int:
+		*p = v.i
+	case *float64:
+		*p = v.f
+	}
+
+	return t
+}
+
+// Mode returns the Mode the Value was produced in.
+func (v Value) Mode() Mode {
+	return v.mode
+}
+
+// Int returns the Value as an int, truncating any fractional part.
+func (v Value) Int() int {
+	return v.i
+}
+
+// Float returns the Value as a float64.
+func (v Value) Float() float64 {
+	return v.f
+}
+
+// In returns v converted to mode.
+func (v Value) In(mode Mode) Value {
+	if mode == FloatMode {
+		return Float(v.f)
+	}
+
+	return Int(v.i)
+}
+
+// Format formats the Value according to o.
+func (v Value) Format(o format.Options) (string, error) {
+	if v.mode == FloatMode {
+		return format.Float(v.f, o)
+	}
+
+	return format.Int(v.i, o)
+}
+
+// String formats the Value with format.Default.
+func (v Value) String() string {
+	s, err := v.Format(format.Default)
+	if err != nil {
+		return fmt.Sprintf("%%!(%v)", err)
+	}
+
+	return s
+}
@@ -0,0 +1,16 @@
+package format
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidOptions is wrapped by errors for Options that can't be used to format a value, such as an
+// unsupported Base or a Notation that the Base doesn't support.
+var ErrInvalidOptions = errors.New("invalid format options")
+
+var (
+	errInvalidBase     = fmt.Errorf("%w: invalid base", ErrInvalidOptions)
+	errInvalidNotation = fmt.Errorf("%w: invalid notation", ErrInvalidOptions)
+	errUnsupported     = fmt.Errorf("%w: unsupported format combination", ErrInvalidOptions)
+)
@@ -0,0 +1,210 @@
+package format
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var notationNames = map[string]Notation{
+	"plain": Plain,
+	"sci":   Scientific,
+	"eng":   Engineering,
+}
+
+// ParseNotation converts a notation name as used on the command line ("plain", "sci" or "eng") to a Notation.
+func ParseNotation(s string) (Notation, error) {
+	n, ok := notationNames[s]
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", errInvalidNotation, s)
+	}
+
+	return n, nil
+}
+
+// Int formats an integer result according to o.
+func Int(v int, o Options) (string, error) {
+	base := o.Base
+	if base == 0 {
+		base = 10
+	}
+
+	switch base {
+	case 2, 8, 10, 16:
+	default:
+		return "", fmt.Errorf("%w: %d", errInvalidBase, o.Base)
+	}
+
+	if base != 10 && (o.Notation != Plain || o.Precision > 0) {
+		return "", fmt.Errorf("%w: base %d only supports plain notation without decimal places", errUnsupported, base)
+	}
+
+	switch o.Notation {
+	case Scientific:
+		return strconv.FormatFloat(float64(v), 'e', o.Precision, 64), nil
+	case Engineering:
+		return engineering(float64(v), o.Precision), nil
+	case Plain:
+	default:
+		return "", fmt.Errorf("%w: %d", errInvalidNotation, o.Notation)
+	}
+
+	// Work with the magnitude so that the sign stays in front of any base prefix.
+	var sign string
+	if v < 0 {
+		sign = "-"
+	}
+
+	digits := strconv.FormatUint(uint64(v), base)
+	if v < 0 {
+		digits = strconv.FormatUint(-uint64(v), base)
+	}
+
+	groupSize := 4
+	if base == 10 {
+		groupSize = 3
+	}
+
+	digits = group(strings.ToUpper(digits), o.Separator, groupSize)
+
+	if o.Precision > 0 {
+		digits += "." + strings.Repeat("0", o.Precision)
+	}
+
+	return sign + prefix(base) + digits, nil
+}
+
+// Float formats a floating point result according to o.  Bases other than 10 are only supported
+// for values that are whole numbers.
+func Float(v float64, o Options) (string, error) {
+	if math.IsInf(v, 0) || math.IsNaN(v) {
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	}
+
+	if o.Base != 0 && o.Base != 10 {
+		if v != math.Trunc(v) || math.Abs(v) >= math.MaxInt64 {
+			return "", fmt.Errorf("%w: %v cannot be written in base %d", errUnsupported, v, o.Base)
+		}
+
+		return Int(int(v), o)
+	}
+
+	switch o.Notation {
+	case Scientific:
+		return strconv.FormatFloat(v, 'e', o.Precision, 64), nil
+	case Engineering:
+		return engineering(v, o.Precision), nil
+	case Plain:
+	default:
+		return "", fmt.Errorf("%w: %d", errInvalidNotation, o.Notation)
+	}
+
+	digits := strconv.FormatFloat(v, 'f', o.Precision, 64)
+
+	var sign string
+	if strings.HasPrefix(digits, "-") {
+		sign, digits = "-", digits[1:]
+	}
+
+	whole, fraction, found := strings.Cut(digits, ".")
+
+	digits = group(whole, o.Separator, 3)
+	if found {
+		digits += "." + fraction
+	}
+
+	return sign + digits, nil
+}
+
+// engineering formats v in engineering notation: like scientific notation but with an exponent
+// that is a multiple of three, so the mantissa is in the range [1, 1000).  The digits are taken
+// from strconv's scientific notation and the point is moved, because dividing by a power of ten
+// would introduce rounding errors.
+func engineering(v float64, precision int) string {
+	sci := strconv.FormatFloat(v, 'e', -1, 64)
+	exp := sciExponent(sci)
+
+	if precision >= 0 {
+		// Each place the point moves right needs one more significant digit.  Rounding can carry
+		// into a new exponent, e.g. 999.96 with one decimal place, so check and repeat once if so.
+		sci = strconv.FormatFloat(v, 'e', precision+((exp%3)+3)%3, 64)
+		if e := sciExponent(sci); e != exp {
+			exp = e
+			sci = strconv.FormatFloat(v, 'e', precision+((exp%3)+3)%3, 64)
+		}
+	}
+
+	shift := ((exp % 3) + 3) % 3
+	mantissa, _, _ := strings.Cut(sci, "e")
+
+	var sign string
+	if strings.HasPrefix(mantissa, "-") {
+		sign, mantissa = "-", mantissa[1:]
+	}
+
+	digits := strings.Replace(mantissa, ".", "", 1)
+	if len(digits) < shift+1 {
+		digits += strings.Repeat("0", shift+1-len(digits))
+	}
+
+	result := sign + digits[:shift+1]
+	if len(digits) > shift+1 {
+		result += "." + digits[shift+1:]
+	}
+
+	exp -= shift
+
+	expSign := '+'
+	if exp < 0 {
+		expSign = '-'
+		exp = -exp
+	}
+
+	return fmt.Sprintf("%se%c%02d", result, expSign, exp)
+}
+
+// sciExponent returns the exponent of a number formatted by strconv.FormatFloat with format 'e'.
+func sciExponent(sci string) int {
+	_, exp, _ := strings.Cut(sci, "e")
+	n, _ := strconv.Atoi(exp)
+
+	return n
+}
+
+// group inserts sep between every size digits, counting from the right.
+func group(digits, sep string, size int) string {
+	if sep == "" || len(digits) <= size {
+		return digits
+	}
+
+	var b strings.Builder
+
+	first := len(digits) % size
+	if first == 0 {
+		first = size
+	}
+
+	b.WriteString(digits[:first])
+
+	for i := first; i < len(digits); i += size {
+		b.WriteString(sep)
+		b.WriteString(digits[i : i+size])
+	}
+
+	return b.String()
+}
+
+// prefix returns the literal prefix the lexer expects for numbers in the given base.
+func prefix(base int) string {
+	switch base {
+	case 2:
+		return "0b"
+	case 8:
+		return "0o"
+	case 16:
+		return "0x"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,95 @@
+package format
+
+import (
+	"math"
+	"testing"
+)
+
+func TestInt(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   int
+		opts    Options
+		want    string
+		wantErr bool
+	}{
+		{name: "zero options", value: 14, opts: Options{}, want: "14"},
+		{name: "negative", value: -14, opts: Options{}, want: "-14"},
+		{name: "hex", value: 255, opts: Options{Base: 16}, want: "0xFF"},
+		{name: "negative hex", value: -31, opts: Options{Base: 16}, want: "-0x1F"},
+		{name: "binary", value: 10, opts: Options{Base: 2}, want: "0b1010"},
+		{name: "octal", value: 15, opts: Options{Base: 8}, want: "0o17"},
+		{name: "decimal grouping", value: 1234567, opts: Options{Separator: ","}, want: "1,234,567"},
+		{name: "grouping exact multiple", value: 123456, opts: Options{Separator: ","}, want: "123,456"},
+		{name: "grouping short value", value: 123, opts: Options{Separator: ","}, want: "123"},
+		{name: "binary grouping", value: 255, opts: Options{Base: 2, Separator: "_"}, want: "0b1111_1111"},
+		{name: "fixed decimals", value: 14, opts: Options{Precision: 2}, want: "14.00"},
+		{name: "grouping and decimals", value: -1000, opts: Options{Separator: ",", Precision: 1}, want: "-1,000.0"},
+		{name: "scientific", value: 12345, opts: Options{Notation: Scientific, Precision: AutoPrecision}, want: "1.2345e+04"},
+		{name: "scientific fixed", value: 12345, opts: Options{Notation: Scientific, Precision: 2}, want: "1.23e+04"},
+		{name: "engineering", value: 12345, opts: Options{Notation: Engineering, Precision: AutoPrecision}, want: "12.345e+03"},
+		{name: "engineering small", value: 7, opts: Options{Notation: Engineering, Precision: AutoPrecision}, want: "7e+00"},
+		{name: "engineering rounding carry", value: 999999, opts: Options{Notation: Engineering, Precision: 1}, want: "1.0e+06"},
+		{name: "engineering zero", value: 0, opts: Options{Notation: Engineering, Precision: 1}, want: "0.0e+00"},
+		{name: "error - invalid base", value: 1, opts: Options{Base: 3}, wantErr: true},
+		{name: "error - scientific in hex", value: 1, opts: Options{Base: 16, Notation: Scientific}, wantErr: true},
+		{name: "error - decimals in binary", value: 1, opts: Options{Base: 2, Precision: 2}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Int(tt.value, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Int() err=%v wantErr=%v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("Int() got=%q want=%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseNotation(t *testing.T) {
+	for name, want := range notationNames {
+		got, err := ParseNotation(name)
+		if err != nil || got != want {
+			t.Fatalf("ParseNotation(%q) = (%v,%v), want (%v,nil)", name, got, err, want)
+		}
+	}
+
+	if _, err := ParseNotation("roman"); err == nil {
+		t.Fatalf("ParseNotation(%q) expected an error", "roman")
+	}
+}
+
+func TestFloat(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   float64
+		opts    Options
+		want    string
+		wantErr bool
+	}{
+		{name: "auto precision", value: 3.5, opts: Options{Precision: AutoPrecision}, want: "3.5"},
+		{name: "whole number", value: 14, opts: Options{Precision: AutoPrecision}, want: "14"},
+		{name: "fixed decimals", value: 2.0 / 3, opts: Options{Precision: 3}, want: "0.667"},
+		{name: "grouping", value: -1234567.25, opts: Options{Separator: ",", Precision: AutoPrecision}, want: "-1,234,567.25"},
+		{name: "scientific", value: 0.00012, opts: Options{Notation: Scientific, Precision: AutoPrecision}, want: "1.2e-04"},
+		{name: "engineering negative exponent", value: 0.00012, opts: Options{Notation: Engineering, Precision: AutoPrecision}, want: "120e-06"},
+		{name: "infinity", value: math.Inf(1), opts: Options{Precision: 2}, want: "+Inf"},
+		{name: "whole number in hex", value: 255, opts: Options{Base: 16}, want: "0xFF"},
+		{name: "error - fraction in hex", value: 2.5, opts: Options{Base: 16}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Float(tt.value, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Float() err=%v wantErr=%v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("Float() got=%q want=%q", got, tt.want)
+			}
+		})
+	}
+}
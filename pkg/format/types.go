@@ -0,0 +1,30 @@
+package format
+
+// Notation selects how a result is written out.
+type Notation int8
+
+const (
+	Plain       Notation = iota // Plain writes the digits of the value, e.g. 12345
+	Scientific                  // Scientific writes one digit before the point, e.g. 1.2345e+04
+	Engineering                 // Engineering uses exponents that are multiples of three, e.g. 12.345e+03
+)
+
+// AutoPrecision tells the formatter to use as many decimal places as are needed to represent the value.
+const AutoPrecision = -1
+
+// Options controls how results are formatted.  Note that the zero value has a Precision of
+// zero decimal places; use Default to format a result exactly as fmt.Println would.
+type Options struct {
+	// Base is the radix used for Plain notation and may be 2, 8, 10 or 16.  Zero means 10.
+	Base int
+	// Notation is the style used to write the value.  Scientific and Engineering require base 10.
+	Notation Notation
+	// Separator, if not empty, is inserted between groups of digits in the integer part of the value.
+	// Decimal digits are grouped in threes and digits in other bases in fours.
+	Separator string
+	// Precision is the fixed number of digits written after the decimal point, or AutoPrecision.
+	Precision int
+}
+
+// Default writes results in plain decimal with as many decimal places as are needed.
+var Default = Options{Base: 10, Notation: Plain, Precision: AutoPrecision}
@@ -0,0 +1,271 @@
+package language
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/parser"
+)
+
+// Implementations lists the names of the built-in operation implementations that an Operator may use.
+// Modulo takes the sign of the divisor, as in Python, and remainder takes the sign of the dividend, as in C.
+// The bitwise implementations accept only whole numbers.  With Parser[int], a result that doesn't fit in an
+// int is an error rather than wrapping around.
+var Implementations = []string{
+	"add", "subtract", "multiply", "divide", "power", "factorial", "percent", "negate",
+	"modulo", "remainder", "floordivide", "bitand", "bitor", "bitxor", "bitnot", "shiftleft", "shiftright",
+}
+
+// Builtin returns an Operation with Fn set for binary implementations or UnaryFn set for unary
+// implementations.  The caller is responsible for setting Description and TokenId.
+func Builtin[T parser.Number](name string) (parser.Operation[T], error) {
+	var op parser.Operation[T]
+
+	switch name {
+	case "add":
+		op.Fn = add[T]
+	case "subtract":
+		op.Fn = subtract[T]
+	case "multiply":
+		op.Fn = multiply[T]
+	case "divide":
+		op.Fn = func(a, b T) (T, error) {
+			if b == 0 {
+				return 0, errDivisionByZero
+			}
+
+			if isMinInt(a) && b == -1 {
+				return 0, fmt.Errorf("%w: %v / %v", errOverflow, a, b)
+			}
+
+			return a / b, nil
+		}
+	case "floordivide":
+		op.Fn = floorDivide[T]
+	case "modulo":
+		op.Fn = modulo[T]
+	case "remainder":
+		op.Fn = remainder[T]
+	case "power":
+		op.Fn = power[T]
+	case "factorial":
+		op.UnaryFn = factorial[T]
+	case "percent":
+		// Like divide, this truncates for ints, so 20% is 0 and 250% is 2.
+		op.UnaryFn = func(a T) (T, error) { return a / 100, nil }
+	case "negate":
+		op.UnaryFn = func(a T) (T, error) {
+			if isMinInt(a) {
+				return 0, fmt.Errorf("%w: -(%v)", errOverflow, a)
+			}
+
+			return -a, nil
+		}
+	case "bitand":
+		op.Fn = bitwise[T](func(a, b int) (int, error) { return a & b, nil })
+	case "bitor":
+		op.Fn = bitwise[T](func(a, b int) (int, error) { return a | b, nil })
+	case "bitxor":
+		op.Fn = bitwise[T](func(a, b int) (int, error) { return a ^ b, nil })
+	case "shiftleft":
+		op.Fn = bitwise[T](func(a, b int) (int, error) {
+			if b < 0 {
+				return 0, fmt.Errorf("negative shift count %d", b)
+			}
+
+			if b >= 64 && a != 0 || a<<b>>b != a {
+				return 0, fmt.Errorf("%w: %d << %d", errOverflow, a, b)
+			}
+
+			return a << b, nil
+		})
+	case "shiftright":
+		op.Fn = bitwise[T](func(a, b int) (int, error) {
+			if b < 0 {
+				return 0, fmt.Errorf("negative shift count %d", b)
+			}
+
+			return a >> b, nil
+		})
+	case "bitnot":
+		op.UnaryFn = func(a T) (T, error) {
+			n, err := whole(a)
+			if err != nil {
+				return 0, fmt.Errorf("bitwise operation: %w", err)
+			}
+
+			return T(^n), nil
+		}
+	default:
+		return op, fmt.Errorf("%w: %q, expected one of %v", errUnknownImplementation, name, Implementations)
+	}
+
+	return op, nil
+}
+
+// add returns a+b, or an error if T is int and the sum overflows.
+func add[T parser.Number](a, b T) (T, error) {
+	r := a + b
+	if integer[T]() && (b > 0 && r < a || b < 0 && r > a) {
+		return 0, fmt.Errorf("%w: %v + %v", errOverflow, a, b)
+	}
+
+	return r, nil
+}
+
+// subtract returns a-b, or an error if T is int and the difference overflows.
+func subtract[T parser.Number](a, b T) (T, error) {
+	r := a - b
+	if integer[T]() && (b > 0 && r > a || b < 0 && r < a) {
+		return 0, fmt.Errorf("%w: %v - %v", errOverflow, a, b)
+	}
+
+	return r, nil
+}
+
+// multiply returns a*b, or an error if T is int and the product overflows, which dividing it by a detects.
+func multiply[T parser.Number](a, b T) (T, error) {
+	r := a * b
+	if integer[T]() && a != 0 && (r/a != b || a == -1 && isMinInt(b)) {
+		return 0, fmt.Errorf("%w: %v * %v", errOverflow, a, b)
+	}
+
+	return r, nil
+}
+
+// power returns a to the power b, or an error if T is int and the result is outside its range.
+func power[T parser.Number](a, b T) (T, error) {
+	r := math.Pow(float64(a), float64(b))
+	if integer[T]() && !inRange(r) {
+		return 0, fmt.Errorf("%w: %v ^ %v", errOverflow, a, b)
+	}
+
+	return T(r), nil
+}
+
+// integer reports whether T is int, whose arithmetic wraps around on overflow instead of giving ±Inf.
+func integer[T parser.Number]() bool {
+	return T(1)/2 == 0
+}
+
+// inRange reports whether x can be converted to an int without overflowing.  Converting a float64 outside
+// that range to an int gives an implementation-defined result, such as the most negative int.
+func inRange(x float64) bool {
+	return x >= math.MinInt64 && x < math.MaxInt64
+}
+
+// isMinInt reports whether v is the most negative int, which has no positive counterpart.
+func isMinInt[T parser.Number](v T) bool {
+	x, ok := any(v).(int)
+
+	return ok && x == math.MinInt
+}
+
+// factorial returns n!, refusing negative or fractional arguments and results that overflow T.
+func factorial[T parser.Number](n T) (T, error) {
+	if n < 0 {
+		return 0, fmt.Errorf("factorial of negative number %v", n)
+	}
+
+	if float64(n) != math.Trunc(float64(n)) {
+		return 0, fmt.Errorf("factorial of non-integer %v", n)
+	}
+
+	// Integer overflow wraps, so dividing by i no longer recovers result.
+	// Floating point overflow gives +Inf instead.
+	result := T(1)
+	for i := T(2); i <= n; i++ {
+		next := result * i
+		if integer[T]() && next/i != result || math.IsInf(float64(next), 0) {
+			return 0, fmt.Errorf("factorial of %v overflows", n)
+		}
+
+		result = next
+	}
+
+	return result, nil
+}
+
+// floorDivide returns a/b rounded towards negative infinity, e.g. -7//2 is -4.
+func floorDivide[T parser.Number](a, b T) (T, error) {
+	if b == 0 {
+		return 0, errDivisionByZero
+	}
+
+	if x, ok := any(a).(int); ok {
+		y := any(b).(int)
+
+		if x == math.MinInt && y == -1 {
+			return 0, fmt.Errorf("%w: %v // %v", errOverflow, a, b)
+		}
+
+		q := x / y
+		if x%y != 0 && (x < 0) != (y < 0) {
+			q--
+		}
+
+		return T(q), nil
+	}
+
+	return T(math.Floor(float64(a) / float64(b))), nil
+}
+
+// remainder returns the remainder of a/b with the sign of a, e.g. -7%2 is -1.
+func remainder[T parser.Number](a, b T) (T, error) {
+	if b == 0 {
+		return 0, errDivisionByZero
+	}
+
+	if x, ok := any(a).(int); ok {
+		return T(x % any(b).(int)), nil
+	}
+
+	return T(math.Mod(float64(a), float64(b))), nil
+}
+
+// modulo returns the remainder of a/b with the sign of b, e.g. -7%2 is 1.
+func modulo[T parser.Number](a, b T) (T, error) {
+	r, err := remainder(a, b)
+	if err != nil {
+		return 0, err
+	}
+
+	if r != 0 && (r < 0) != (b < 0) {
+		r += b
+	}
+
+	return r, nil
+}
+
+// bitwise adapts fn, which works on ints, to T by refusing operands that aren't whole numbers.
+func bitwise[T parser.Number](fn func(a, b int) (int, error)) parser.OperationFn[T] {
+	return func(a, b T) (T, error) {
+		x, err := whole(a)
+		if err != nil {
+			return 0, fmt.Errorf("bitwise operation: %w", err)
+		}
+
+		y, err := whole(b)
+		if err != nil {
+			return 0, fmt.Errorf("bitwise operation: %w", err)
+		}
+
+		result, err := fn(x, y)
+
+		return T(result), err
+	}
+}
+
+// whole returns v as an int, or an error if v has a fractional part or is out of range.
+func whole[T parser.Number](v T) (int, error) {
+	f := float64(v)
+	if f != math.Trunc(f) {
+		return 0, fmt.Errorf("%v isn't a whole number", v)
+	}
+
+	if !inRange(f) && !integer[T]() {
+		return 0, fmt.Errorf("%w: %v", errOutOfRange, v)
+	}
+
+	return int(v), nil
+}
@@ -0,0 +1,61 @@
+package language
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestBuiltinOverflow(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []int
+		want    int
+		wantErr error
+	}{
+		{name: "add", args: []int{math.MaxInt - 1, 1}, want: math.MaxInt},
+		{name: "add", args: []int{math.MaxInt, 1}, wantErr: errOverflow},
+		{name: "add", args: []int{math.MinInt, -1}, wantErr: errOverflow},
+		{name: "subtract", args: []int{math.MinInt + 1, 1}, want: math.MinInt},
+		{name: "subtract", args: []int{math.MinInt, 1}, wantErr: errOverflow},
+		{name: "subtract", args: []int{0, math.MinInt}, wantErr: errOverflow},
+		{name: "multiply", args: []int{-3, 7}, want: -21},
+		{name: "multiply", args: []int{1 << 32, 1 << 32}, wantErr: errOverflow},
+		{name: "multiply", args: []int{-1, math.MinInt}, wantErr: errOverflow},
+		{name: "multiply", args: []int{math.MinInt, -1}, wantErr: errOverflow},
+		{name: "divide", args: []int{math.MinInt, -1}, wantErr: errOverflow},
+		{name: "floordivide", args: []int{math.MinInt, -1}, wantErr: errOverflow},
+		{name: "power", args: []int{2, 62}, want: 1 << 62},
+		{name: "power", args: []int{2, 63}, wantErr: errOverflow},
+		{name: "power", args: []int{-2, 63}, want: math.MinInt},
+		{name: "shiftleft", args: []int{1, 62}, want: 1 << 62},
+		{name: "shiftleft", args: []int{3, 62}, wantErr: errOverflow},
+		{name: "shiftleft", args: []int{1, 64}, wantErr: errOverflow},
+		{name: "shiftleft", args: []int{0, 64}, want: 0},
+		{name: "negate", args: []int{math.MinInt}, wantErr: errOverflow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			op, err := Builtin[int](tt.name)
+			if err != nil {
+				t.Fatalf("Builtin() err=%v", err)
+			}
+
+			var got int
+			if op.UnaryFn != nil {
+				got, err = op.UnaryFn(tt.args[0])
+			} else {
+				got, err = op.Fn(tt.args[0], tt.args[1])
+			}
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("%s%v err=%v wantErr=%v", tt.name, tt.args, err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Fatalf("%s%v = %d, want %d", tt.name, tt.args, got, tt.want)
+			}
+		})
+	}
+}
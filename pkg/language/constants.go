@@ -0,0 +1,59 @@
+package language
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/parser"
+)
+
+// MathConstants holds the constants that every Language has.  A Language's own Constants may not redefine them.
+var MathConstants = map[string]float64{
+	"pi":  math.Pi,
+	"e":   math.E,
+	"tau": 2 * math.Pi,
+	"phi": math.Phi,
+}
+
+// Constants returns MathConstants and l's Constants converted to T, so with Parser[int] pi is 3.
+// It returns an error if l redefines one of MathConstants.
+func Constants[T parser.Number](l Language) (map[string]T, error) {
+	constants := make(map[string]T, len(MathConstants)+len(l.Constants))
+	for name, v := range MathConstants {
+		constants[name] = T(v)
+	}
+
+	for _, name := range sortedKeys(l.Constants) {
+		if _, ok := MathConstants[name]; ok {
+			return nil, fmt.Errorf("%w: %s is built in", errInvalidConstant, name)
+		}
+
+		constants[name] = T(l.Constants[name])
+	}
+
+	return constants, nil
+}
+
+// validateConstants checks that l's Constants have valid names and finite values.
+func validateConstants(l Language) error {
+	for _, name := range sortedKeys(l.Constants) {
+		if !isName(name) {
+			return fmt.Errorf("%w: %q isn't a valid name", errInvalidConstant, name)
+		}
+
+		if v := l.Constants[name]; math.IsInf(v, 0) || math.IsNaN(v) {
+			return fmt.Errorf("%w: %s is %v", errInvalidConstant, name, v)
+		}
+	}
+
+	_, err := Constants[int](l)
+
+	return err
+}
+
+// sortedKeys returns the keys of m in sorted order, so that errors are reported deterministically.
+func sortedKeys(m map[string]float64) []string {
+	return slices.Sorted(maps.Keys(m))
+}
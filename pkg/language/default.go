@@ -0,0 +1,47 @@
+package language
+
+import (
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/lexer"
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/parser"
+)
+
+// Default returns a new copy of the language used when no other is chosen.  It has + - * / ^, the postfix
+// operators ! and %, ^ is right associative and the others left associative, and there is no unary
+// minus.  Implicit multiplication has an Operator, so it can be enabled without defining one, and the
+// functions are those returned by Library.
+func Default() *Language {
+	return &Language{
+		Tokens: []lexer.Token{
+			{Id: lexer.Plus, Value: "+"},
+			{Id: lexer.Minus, Value: "-"},
+			{Id: lexer.Multiply, Value: "*"},
+			{Id: lexer.Divide, Value: "/"},
+			{Id: lexer.Exponent, Value: "^"},
+			{Id: lexer.Factorial, Value: "!"},
+			{Id: lexer.Percent, Value: "%"},
+			{Id: lexer.LParen, Value: "("},
+			{Id: lexer.RParen, Value: ")"},
+			{Id: lexer.Assign, Value: "="},
+			{Id: lexer.Semicolon, Value: ";"},
+			{Id: lexer.Comma, Value: ","},
+		},
+		Operators: []Operator{
+			{Description: "Plus", TokenId: lexer.Plus, Implementation: "add"},
+			{Description: "Minus", TokenId: lexer.Minus, Implementation: "subtract"},
+			{Description: "Multiply", TokenId: lexer.Multiply, Implementation: "multiply"},
+			{Description: "ImplicitMultiply", TokenId: lexer.ImplicitMultiply, Implementation: "multiply"},
+			{Description: "Divide", TokenId: lexer.Divide, Implementation: "divide"},
+			{Description: "Exponent", TokenId: lexer.Exponent, Implementation: "power"},
+			{Description: "Factorial", TokenId: lexer.Factorial, Implementation: "factorial"},
+			{Description: "Percent", TokenId: lexer.Percent, Implementation: "percent"},
+		},
+		OperationGroups: []parser.OperationGroup{
+			{Tokens: []lexer.TokenId{lexer.Factorial, lexer.Percent}, Precedence: parser.PrecedencePostfix, Associativity: parser.LeftAssociative, Fixity: parser.Postfix},
+			{Tokens: []lexer.TokenId{lexer.Exponent}, Precedence: parser.PrecedenceExponent, Associativity: parser.RightAssociative},
+			{Tokens: []lexer.TokenId{lexer.ImplicitMultiply}, Precedence: parser.PrecedenceImplicitMultiply, Associativity: parser.LeftAssociative},
+			{Tokens: []lexer.TokenId{lexer.Multiply, lexer.Divide}, Precedence: parser.PrecedenceMultiplyDivide, Associativity: parser.LeftAssociative},
+			{Tokens: []lexer.TokenId{lexer.Plus, lexer.Minus}, Precedence: parser.PrecedencePlusMinus, Associativity: parser.LeftAssociative},
+		},
+		Functions: Library(),
+	}
+}
@@ -0,0 +1,10 @@
+package language
+
+import "testing"
+
+// TestDefaultIsValid checks the default language, so that applying it can't fail.
+func TestDefaultIsValid(t *testing.T) {
+	if err := Validate(*Default()); err != nil {
+		t.Fatalf("Validate() err=%v", err)
+	}
+}
@@ -0,0 +1,199 @@
+package language
+
+import (
+	"fmt"
+
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/lexer"
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/parser"
+)
+
+// Dialects lists the names of the preset Languages returned by Dialect.
+var Dialects = []string{"c", "python", "spreadsheet", "textbook"}
+
+// Dialect returns a new copy of the named preset Language:
+//
+//   - "c" has %, the bitwise operators & | ^ ~ << >> and C's precedence, and no exponent operator.
+//   - "python" adds ** and // to C's operators, and % takes the sign of the divisor.  -2**2 is -4.
+//   - "spreadsheet" has ^, which is left associative, and percent, and unary minus binds most tightly,
+//     so -2^2 is 4.
+//   - "textbook" accepts × and ÷ as well as * and /, ^ and !, and implicit multiplication, so 2(3+4) is 14.
+//
+// All of them have unary minus and the functions returned by Library.
+func Dialect(name string) (*Language, error) {
+	var l Language
+
+	switch name {
+	case "c":
+		l = c()
+	case "python":
+		l = python()
+	case "spreadsheet":
+		l = spreadsheet()
+	case "textbook":
+		l = textbook()
+	default:
+		return nil, fmt.Errorf("%w: %q, expected one of %v", errUnknownDialect, name, Dialects)
+	}
+
+	return &l, nil
+}
+
+func c() Language {
+	return Language{
+		Tokens: []lexer.Token{
+			{Id: lexer.Plus, Value: "+"},
+			{Id: lexer.Minus, Value: "-"},
+			{Id: lexer.Multiply, Value: "*"},
+			{Id: lexer.Divide, Value: "/"},
+			{Id: lexer.Modulo, Value: "%"},
+			{Id: lexer.BitwiseAnd, Value: "&"},
+			{Id: lexer.BitwiseOr, Value: "|"},
+			{Id: lexer.BitwiseXor, Value: "^"},
+			{Id: lexer.BitwiseNot, Value: "~"},
+			{Id: lexer.ShiftLeft, Value: "<<"},
+			{Id: lexer.ShiftRight, Value: ">>"},
+			{Id: lexer.LParen, Value: "("},
+			{Id: lexer.RParen, Value: ")"},
+			{Id: lexer.Assign, Value: "="},
+			{Id: lexer.Semicolon, Value: ";"},
+			{Id: lexer.Comma, Value: ","},
+		},
+		Operators: []Operator{
+			{Description: "Plus", TokenId: lexer.Plus, Implementation: "add"},
+			{Description: "Minus", TokenId: lexer.Minus, Implementation: "subtract", Prefix: "negate"},
+			{Description: "Multiply", TokenId: lexer.Multiply, Implementation: "multiply"},
+			{Description: "Divide", TokenId: lexer.Divide, Implementation: "divide"},
+			{Description: "Modulo", TokenId: lexer.Modulo, Implementation: "remainder"},
+			{Description: "BitwiseAnd", TokenId: lexer.BitwiseAnd, Implementation: "bitand"},
+			{Description: "BitwiseOr", TokenId: lexer.BitwiseOr, Implementation: "bitor"},
+			{Description: "BitwiseXor", TokenId: lexer.BitwiseXor, Implementation: "bitxor"},
+			{Description: "BitwiseNot", TokenId: lexer.BitwiseNot, Prefix: "bitnot"},
+			{Description: "ShiftLeft", TokenId: lexer.ShiftLeft, Implementation: "shiftleft"},
+			{Description: "ShiftRight", TokenId: lexer.ShiftRight, Implementation: "shiftright"},
+		},
+		OperationGroups: inOrder(
+			parser.OperationGroup{Tokens: []lexer.TokenId{lexer.Minus, lexer.BitwiseNot}, Fixity: parser.Prefix},
+			parser.OperationGroup{Tokens: []lexer.TokenId{lexer.Multiply, lexer.Divide, lexer.Modulo}},
+			parser.OperationGroup{Tokens: []lexer.TokenId{lexer.Plus, lexer.Minus}},
+			parser.OperationGroup{Tokens: []lexer.TokenId{lexer.ShiftLeft, lexer.ShiftRight}},
+			parser.OperationGroup{Tokens: []lexer.TokenId{lexer.BitwiseAnd}},
+			parser.OperationGroup{Tokens: []lexer.TokenId{lexer.BitwiseXor}},
+			parser.OperationGroup{Tokens: []lexer.TokenId{lexer.BitwiseOr}},
+		),
+		Functions: Library(),
+	}
+}
+
+func python() Language {
+	l := c()
+
+	l.Tokens = append(l.Tokens,
+		lexer.Token{Id: lexer.Exponent, Value: "**"},
+		lexer.Token{Id: lexer.FloorDivide, Value: "//"},
+	)
+
+	for i := range l.Operators {
+		if l.Operators[i].TokenId == lexer.Modulo {
+			l.Operators[i].Implementation = "modulo"
+		}
+	}
+
+	l.Operators = append(l.Operators,
+		Operator{Description: "Exponent", TokenId: lexer.Exponent, Implementation: "power"},
+		Operator{Description: "FloorDivide", TokenId: lexer.FloorDivide, Implementation: "floordivide"},
+	)
+
+	// Unlike C, unary minus binds less tightly than the exponent operator on its right.
+	l.OperationGroups = inOrder(
+		parser.OperationGroup{Tokens: []lexer.TokenId{lexer.Exponent}, Associativity: parser.RightAssociative},
+		parser.OperationGroup{Tokens: []lexer.TokenId{lexer.Minus, lexer.BitwiseNot}, Fixity: parser.Prefix},
+		parser.OperationGroup{Tokens: []lexer.TokenId{lexer.Multiply, lexer.Divide, lexer.FloorDivide, lexer.Modulo}},
+		parser.OperationGroup{Tokens: []lexer.TokenId{lexer.Plus, lexer.Minus}},
+		parser.OperationGroup{Tokens: []lexer.TokenId{lexer.ShiftLeft, lexer.ShiftRight}},
+		parser.OperationGroup{Tokens: []lexer.TokenId{lexer.BitwiseAnd}},
+		parser.OperationGroup{Tokens: []lexer.TokenId{lexer.BitwiseXor}},
+		parser.OperationGroup{Tokens: []lexer.TokenId{lexer.BitwiseOr}},
+	)
+
+	return l
+}
+
+func spreadsheet() Language {
+	return Language{
+		Tokens: []lexer.Token{
+			{Id: lexer.Plus, Value: "+"},
+			{Id: lexer.Minus, Value: "-"},
+			{Id: lexer.Multiply, Value: "*"},
+			{Id: lexer.Divide, Value: "/"},
+			{Id: lexer.Exponent, Value: "^"},
+			{Id: lexer.Percent, Value: "%"},
+			{Id: lexer.LParen, Value: "("},
+			{Id: lexer.RParen, Value: ")"},
+			{Id: lexer.Assign, Value: "="},
+			{Id: lexer.Semicolon, Value: ";"},
+			{Id: lexer.Comma, Value: ","},
+		},
+		Operators: []Operator{
+			{Description: "Plus", TokenId: lexer.Plus, Implementation: "add"},
+			{Description: "Minus", TokenId: lexer.Minus, Implementation: "subtract", Prefix: "negate"},
+			{Description: "Multiply", TokenId: lexer.Multiply, Implementation: "multiply"},
+			{Description: "Divide", TokenId: lexer.Divide, Implementation: "divide"},
+			{Description: "Exponent", TokenId: lexer.Exponent, Implementation: "power"},
+			{Description: "Percent", TokenId: lexer.Percent, Implementation: "percent"},
+		},
+		OperationGroups: inOrder(
+			parser.OperationGroup{Tokens: []lexer.TokenId{lexer.Minus}, Fixity: parser.Prefix},
+			parser.OperationGroup{Tokens: []lexer.TokenId{lexer.Percent}, Fixity: parser.Postfix},
+			parser.OperationGroup{Tokens: []lexer.TokenId{lexer.Exponent}},
+			parser.OperationGroup{Tokens: []lexer.TokenId{lexer.Multiply, lexer.Divide}},
+			parser.OperationGroup{Tokens: []lexer.TokenId{lexer.Plus, lexer.Minus}},
+		),
+		Functions: Library(),
+	}
+}
+
+func textbook() Language {
+	return Language{
+		Tokens: []lexer.Token{
+			{Id: lexer.Plus, Value: "+"},
+			{Id: lexer.Minus, Value: "-"},
+			{Id: lexer.Multiply, Value: "*"},
+			{Id: lexer.Multiply, Value: "×"},
+			{Id: lexer.Divide, Value: "/"},
+			{Id: lexer.Divide, Value: "÷"},
+			{Id: lexer.Exponent, Value: "^"},
+			{Id: lexer.Factorial, Value: "!"},
+			{Id: lexer.LParen, Value: "("},
+			{Id: lexer.RParen, Value: ")"},
+			{Id: lexer.Assign, Value: "="},
+			{Id: lexer.Semicolon, Value: ";"},
+			{Id: lexer.Comma, Value: ","},
+		},
+		Operators: []Operator{
+			{Description: "Plus", TokenId: lexer.Plus, Implementation: "add"},
+			{Description: "Minus", TokenId: lexer.Minus, Implementation: "subtract", Prefix: "negate"},
+			{Description: "Multiply", TokenId: lexer.Multiply, Implementation: "multiply"},
+			{Description: "Divide", TokenId: lexer.Divide, Implementation: "divide"},
+			{Description: "Exponent", TokenId: lexer.Exponent, Implementation: "power"},
+			{Description: "Factorial", TokenId: lexer.Factorial, Implementation: "factorial"},
+		},
+		OperationGroups: inOrder(
+			parser.OperationGroup{Tokens: []lexer.TokenId{lexer.Factorial}, Fixity: parser.Postfix},
+			parser.OperationGroup{Tokens: []lexer.TokenId{lexer.Exponent}, Associativity: parser.RightAssociative},
+			parser.OperationGroup{Tokens: []lexer.TokenId{lexer.Minus}, Fixity: parser.Prefix},
+			parser.OperationGroup{Tokens: []lexer.TokenId{lexer.Multiply, lexer.Divide}},
+			parser.OperationGroup{Tokens: []lexer.TokenId{lexer.Plus, lexer.Minus}},
+		),
+		ImplicitMultiplication: lexer.Multiply,
+		Functions:              Library(),
+	}
+}
+
+// inOrder returns groups with each Precedence set to its index, as Load does.
+func inOrder(groups ...parser.OperationGroup) []parser.OperationGroup {
+	for i := range groups {
+		groups[i].Precedence = parser.Precedence(i)
+	}
+
+	return groups
+}
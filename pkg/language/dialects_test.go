@@ -0,0 +1,87 @@
+package language
+
+import (
+	"testing"
+
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/lexer"
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/parser"
+)
+
+func TestDialects(t *testing.T) {
+	tests := []struct {
+		dialect string
+		input   string
+		want    int
+		wantErr bool
+	}{
+		{dialect: "c", input: "-7 % 3", want: -1},
+		{dialect: "c", input: "1 + 2 << 3", want: 24},
+		{dialect: "c", input: "6 & 3 | 8 ^ 12", want: 6},
+		{dialect: "c", input: "~0 - -1", want: 0},
+		{dialect: "c", input: "2 ^ 3", want: 1},
+		{dialect: "c", input: "1 << -1", wantErr: true},
+		{dialect: "python", input: "-7 % 3", want: 2},
+		{dialect: "python", input: "-7 // 2", want: -4},
+		{dialect: "python", input: "-2**2", want: -4},
+		{dialect: "python", input: "2**3**2", want: 512},
+		{dialect: "python", input: "7 // 0", wantErr: true},
+		{dialect: "spreadsheet", input: "-2^2", want: 4},
+		{dialect: "spreadsheet", input: "2^3^2", want: 64},
+		{dialect: "spreadsheet", input: "300%-1", want: 2},
+		{dialect: "textbook", input: "2(3+4) × 2 ÷ 7", want: 4},
+		{dialect: "textbook", input: "-3!", want: -6},
+		{dialect: "textbook", input: "-2^2", want: -4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dialect+" "+tt.input, func(t *testing.T) {
+			l, err := Dialect(tt.dialect)
+			if err != nil {
+				t.Fatalf("Dialect() err=%v", err)
+			}
+
+			ops, err := Operations[int](*l)
+			if err != nil {
+				t.Fatalf("Operations() err=%v", err)
+			}
+
+			p, err := parser.NewParser(ops, l.OperationGroups, parser.WithImplicitMultiplication(l.ImplicitMultiplication))
+			if err != nil {
+				t.Fatalf("NewParser() err=%v", err)
+			}
+
+			elements, err := lexer.NewLexer(tt.input, l.Tokens).GetElementList()
+			if err != nil {
+				t.Fatalf("GetElementList() err=%v", err)
+			}
+
+			got, err := p.Eval(elements)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Eval() err=%v wantErr=%v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if *got != tt.want {
+				t.Fatalf("Eval() got=%d want=%d", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDialectsAreValid(t *testing.T) {
+	for _, name := range Dialects {
+		l, err := Dialect(name)
+		if err != nil {
+			t.Fatalf("Dialect(%q) err=%v", name, err)
+		}
+
+		if err := Validate(*l); err != nil {
+			t.Fatalf("Validate(%q) err=%v", name, err)
+		}
+	}
+
+	if _, err := Dialect("fortran"); err == nil {
+		t.Fatalf("Dialect(\"fortran\") err=nil, want an error")
+	}
+}
@@ -0,0 +1,22 @@
+package language
+
+import "errors"
+
+var (
+	errConflictingToken      = errors.New("conflicting token")
+	errUnknownOperator       = errors.New("unknown operator")
+	errUnknownImplementation = errors.New("unknown implementation")
+	errDuplicateOperator     = errors.New("duplicate operator")
+	errUngroupedOperator     = errors.New("operator is not in exactly one group")
+	errUnspelledOperator     = errors.New("operator has no token")
+	errInvalidGroup          = errors.New("invalid operation group")
+	errTooManyOperators      = errors.New("too many operators")
+	errInvalidOperator       = errors.New("invalid operator")
+	errUnknownDialect        = errors.New("unknown dialect")
+	errInvalidFunction       = errors.New("invalid function")
+	errInvalidConstant       = errors.New("invalid constant")
+
+	errDivisionByZero = errors.New("division by zero")
+	errOverflow       = errors.New("integer overflow")
+	errOutOfRange     = errors.New("out of range")
+)
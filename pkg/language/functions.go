@@ -0,0 +1,293 @@
+package language
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/parser"
+)
+
+// MathFunctions lists the names of the built-in function implementations that a Function may use.
+// Trigonometric functions work in radians.  round takes an optional number of decimal places, which may
+// be negative, and rounds halves away from zero.  log(b, x) is the logarithm of x to base b.  gcd and
+// lcm accept only whole numbers, and their results must fit in an int.  With Parser[int], results are
+// truncated towards zero, so sqrt(8) is 2.
+var MathFunctions = []string{
+	"abs", "sign", "min", "max", "floor", "ceil", "round", "trunc", "sqrt", "cbrt", "exp", "ln", "log10", "log",
+	"sin", "cos", "tan", "asin", "acos", "atan", "hypot", "gcd", "lcm", "clamp",
+}
+
+// Library returns a Function for each of MathFunctions, named after its implementation.
+func Library() []Function {
+	functions := make([]Function, 0, len(MathFunctions))
+	for _, name := range MathFunctions {
+		functions = append(functions, Function{Name: name, Implementation: name})
+	}
+
+	return functions
+}
+
+// BuiltinFunction returns the named built-in function implementation.  The caller is responsible for
+// setting Name.  Arguments outside a function's domain, such as sqrt(-1), give errors wrapping parser.ErrDomain.
+func BuiltinFunction[T parser.Number](name string) (parser.BuiltinFunction[T], error) {
+	fn := parser.BuiltinFunction[T]{MinArgs: 1, MaxArgs: 1}
+
+	switch name {
+	case "abs":
+		fn.Fn = func(args ...T) (T, error) {
+			if isMinInt(args[0]) {
+				return 0, fmt.Errorf("%w: result -(%v)", errOutOfRange, args[0])
+			}
+
+			return max(args[0], -args[0]), nil
+		}
+	case "sign":
+		fn.Fn = func(args ...T) (T, error) {
+			switch {
+			case args[0] > 0:
+				return 1, nil
+			case args[0] < 0:
+				return -1, nil
+			default:
+				return 0, nil
+			}
+		}
+	case "min":
+		fn.MaxArgs = -1
+		fn.Fn = func(args ...T) (T, error) { return slices.Min(args), nil }
+	case "max":
+		fn.MaxArgs = -1
+		fn.Fn = func(args ...T) (T, error) { return slices.Max(args), nil }
+	case "floor":
+		fn.Fn = unary[T](math.Floor)
+	case "ceil":
+		fn.Fn = unary[T](math.Ceil)
+	case "trunc":
+		fn.Fn = unary[T](math.Trunc)
+	case "round":
+		fn.MaxArgs = 2
+		fn.Fn = round[T]
+	case "sqrt":
+		fn.Fn = unary[T](math.Sqrt, atLeast(0))
+	case "cbrt":
+		fn.Fn = unary[T](math.Cbrt)
+	case "exp":
+		fn.Fn = unary[T](math.Exp)
+	case "ln":
+		fn.Fn = unary[T](math.Log, above(0))
+	case "log10":
+		fn.Fn = unary[T](math.Log10, above(0))
+	case "log":
+		fn.MinArgs, fn.MaxArgs = 2, 2
+		fn.Fn = logarithm[T]
+	case "sin":
+		fn.Fn = unary[T](math.Sin)
+	case "cos":
+		fn.Fn = unary[T](math.Cos)
+	case "tan":
+		fn.Fn = unary[T](math.Tan)
+	case "asin":
+		fn.Fn = unary[T](math.Asin, between(-1, 1))
+	case "acos":
+		fn.Fn = unary[T](math.Acos, between(-1, 1))
+	case "atan":
+		fn.Fn = unary[T](math.Atan)
+	case "hypot":
+		fn.MinArgs, fn.MaxArgs = 2, 2
+		fn.Fn = func(args ...T) (T, error) { return finite[T](math.Hypot(float64(args[0]), float64(args[1]))) }
+	case "gcd":
+		fn.MinArgs, fn.MaxArgs = 2, -1
+		fn.Fn = integers[T](gcd)
+	case "lcm":
+		fn.MinArgs, fn.MaxArgs = 2, -1
+		fn.Fn = integers[T](lcm)
+	case "clamp":
+		fn.MinArgs, fn.MaxArgs = 3, 3
+		fn.Fn = func(args ...T) (T, error) {
+			x, lo, hi := args[0], args[1], args[2]
+			if lo > hi {
+				return 0, fmt.Errorf("%w: lower bound %v is above upper bound %v", parser.ErrDomain, lo, hi)
+			}
+
+			return min(max(x, lo), hi), nil
+		}
+	default:
+		return fn, fmt.Errorf("%w: %q, expected one of %v", errUnknownImplementation, name, MathFunctions)
+	}
+
+	return fn, nil
+}
+
+// Functions builds the parser.BuiltinFunctions for l's Functions from the built-in implementations.
+func Functions[T parser.Number](l Language) ([]parser.BuiltinFunction[T], error) {
+	functions := make([]parser.BuiltinFunction[T], 0, len(l.Functions))
+
+	for _, f := range l.Functions {
+		fn, err := BuiltinFunction[T](f.Implementation)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Name, err)
+		}
+
+		fn.Name = f.Name
+		functions = append(functions, fn)
+	}
+
+	return functions, nil
+}
+
+// domain checks that x is a valid argument for a function, returning an error wrapping parser.ErrDomain if it isn't.
+type domain func(x float64) error
+
+func atLeast(lo float64) domain {
+	return func(x float64) error {
+		if x < lo {
+			return fmt.Errorf("%w: %v is less than %v", parser.ErrDomain, x, lo)
+		}
+
+		return nil
+	}
+}
+
+func above(lo float64) domain {
+	return func(x float64) error {
+		if x <= lo {
+			return fmt.Errorf("%w: %v is not greater than %v", parser.ErrDomain, x, lo)
+		}
+
+		return nil
+	}
+}
+
+func between(lo, hi float64) domain {
+	return func(x float64) error {
+		if x < lo || x > hi {
+			return fmt.Errorf("%w: %v is outside [%v, %v]", parser.ErrDomain, x, lo, hi)
+		}
+
+		return nil
+	}
+}
+
+// unary adapts fn, which works on float64, to a one-argument function of T, checking the argument
+// against any domains first.
+func unary[T parser.Number](fn func(float64) float64, domains ...domain) func(args ...T) (T, error) {
+	return func(args ...T) (T, error) {
+		x := float64(args[0])
+		for _, d := range domains {
+			if err := d(x); err != nil {
+				return 0, err
+			}
+		}
+
+		return finite[T](fn(x))
+	}
+}
+
+// finite converts x to T, or returns an error if it is infinite or not a number, or if T is int and x is
+// outside its range, none of which T(x) can represent.
+func finite[T parser.Number](x float64) (T, error) {
+	if math.IsInf(x, 0) || math.IsNaN(x) || integer[T]() && !inRange(x) {
+		return 0, fmt.Errorf("%w: result %v", errOutOfRange, x)
+	}
+
+	return T(x), nil
+}
+
+// round rounds args[0] to args[1] decimal places, or to a whole number if there is no args[1].
+func round[T parser.Number](args ...T) (T, error) {
+	x := float64(args[0])
+	if len(args) == 1 {
+		return finite[T](math.Round(x))
+	}
+
+	digits, err := whole(args[1])
+	if err != nil {
+		return 0, fmt.Errorf("%w: number of digits: %w", parser.ErrDomain, err)
+	}
+
+	scale := math.Pow(10, float64(digits))
+
+	// A float64 of magnitude 2^52 or more is whole, so there is nothing to round away, however many digits
+	// are asked for, and a scale too large or too small for a float64 would give NaN.
+	switch {
+	case math.IsInf(scale, 0) || math.Abs(x*scale) >= 1<<52:
+		return finite[T](x)
+	case scale == 0:
+		return 0, nil
+	}
+
+	return finite[T](math.Round(x*scale) / scale)
+}
+
+// logarithm returns the logarithm of args[1] to base args[0].
+func logarithm[T parser.Number](args ...T) (T, error) {
+	base, x := float64(args[0]), float64(args[1])
+	if base <= 0 || base == 1 {
+		return 0, fmt.Errorf("%w: invalid base %v", parser.ErrDomain, base)
+	}
+
+	if err := above(0)(x); err != nil {
+		return 0, err
+	}
+
+	return finite[T](math.Log(x) / math.Log(base))
+}
+
+// integers adapts fn, which combines two ints, to a function of T that folds it over its arguments,
+// refusing any that aren't whole numbers and any result that fn can't give as an int.
+func integers[T parser.Number](fn func(a, b int) (int, error)) func(args ...T) (T, error) {
+	return func(args ...T) (T, error) {
+		var result int
+
+		for i, arg := range args {
+			n, err := whole(arg)
+			if err != nil {
+				return 0, fmt.Errorf("%w: %w", parser.ErrDomain, err)
+			}
+
+			if i == 0 {
+				result = n
+			} else if result, err = fn(result, n); err != nil {
+				return 0, fmt.Errorf("%w: %w", parser.ErrDomain, err)
+			}
+		}
+
+		return T(result), nil
+	}
+}
+
+// gcd returns the greatest common divisor of a and b, which is never negative, or an error if it is the
+// negation of MinInt, which overflows.
+func gcd(a, b int) (int, error) {
+	x, y := a, b
+	for y != 0 {
+		x, y = y, x%y
+	}
+
+	if isMinInt(x) {
+		return 0, fmt.Errorf("%w: gcd(%d, %d)", errOverflow, a, b)
+	}
+
+	return max(x, -x), nil
+}
+
+// lcm returns the least common multiple of a and b, which is never negative, or an error if it doesn't fit
+// in an int.
+func lcm(a, b int) (int, error) {
+	if a == 0 || b == 0 {
+		return 0, nil
+	}
+
+	d, err := gcd(a, b)
+	if err != nil {
+		return 0, err
+	}
+
+	l, err := multiply(a/d, b)
+	if err != nil || isMinInt(l) {
+		return 0, fmt.Errorf("%w: lcm(%d, %d)", errOverflow, a, b)
+	}
+
+	return max(l, -l), nil
+}
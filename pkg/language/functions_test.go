@@ -0,0 +1,135 @@
+package language
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/parser"
+)
+
+func TestBuiltinFunction(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []float64
+		want    float64
+		wantErr error
+	}{
+		{name: "abs", args: []float64{-2.5}, want: 2.5},
+		{name: "sign", args: []float64{-0.1}, want: -1},
+		{name: "min", args: []float64{3, -1, 2}, want: -1},
+		{name: "max", args: []float64{3, -1, 2}, want: 3},
+		{name: "floor", args: []float64{-1.5}, want: -2},
+		{name: "ceil", args: []float64{-1.5}, want: -1},
+		{name: "trunc", args: []float64{-1.5}, want: -1},
+		{name: "round", args: []float64{2.5}, want: 3},
+		{name: "round", args: []float64{3.14159, 2}, want: 3.14},
+		{name: "round", args: []float64{1250, -2}, want: 1300},
+		{name: "round", args: []float64{3.14159, 400}, want: 3.14159},
+		{name: "round", args: []float64{0.1, 20}, want: 0.1},
+		{name: "round", args: []float64{1e300, 100}, want: 1e300},
+		{name: "round", args: []float64{1250, -400}, want: 0},
+		{name: "round", args: []float64{1, 0.5}, wantErr: parser.ErrDomain},
+		{name: "sqrt", args: []float64{2.25}, want: 1.5},
+		{name: "sqrt", args: []float64{-1}, wantErr: parser.ErrDomain},
+		{name: "cbrt", args: []float64{-27}, want: -3},
+		{name: "exp", args: []float64{0}, want: 1},
+		{name: "ln", args: []float64{1}, want: 0},
+		{name: "ln", args: []float64{0}, wantErr: parser.ErrDomain},
+		{name: "log10", args: []float64{1000}, want: 3},
+		{name: "log10", args: []float64{-1}, wantErr: parser.ErrDomain},
+		{name: "log", args: []float64{2, 1024}, want: 10},
+		{name: "log", args: []float64{1, 5}, wantErr: parser.ErrDomain},
+		{name: "log", args: []float64{2, 0}, wantErr: parser.ErrDomain},
+		{name: "sin", args: []float64{0}, want: 0},
+		{name: "cos", args: []float64{0}, want: 1},
+		{name: "tan", args: []float64{0}, want: 0},
+		{name: "asin", args: []float64{1}, want: math.Pi / 2},
+		{name: "asin", args: []float64{2}, wantErr: parser.ErrDomain},
+		{name: "acos", args: []float64{1}, want: 0},
+		{name: "atan", args: []float64{0}, want: 0},
+		{name: "hypot", args: []float64{3, 4}, want: 5},
+		{name: "gcd", args: []float64{12, -18, 8}, want: 2},
+		{name: "gcd", args: []float64{1.5, 3}, wantErr: parser.ErrDomain},
+		{name: "gcd", args: []float64{1e19, 2}, wantErr: errOutOfRange},
+		{name: "gcd", args: []float64{math.MinInt, 6}, want: 2},
+		{name: "gcd", args: []float64{math.MinInt, 0}, wantErr: errOverflow},
+		{name: "lcm", args: []float64{4, 6}, want: 12},
+		{name: "lcm", args: []float64{0, 6}, want: 0},
+		{name: "lcm", args: []float64{-4, 6}, want: 12},
+		{name: "lcm", args: []float64{1 << 62, 3}, wantErr: parser.ErrDomain},
+		{name: "lcm", args: []float64{math.MinInt, 1}, wantErr: errOverflow},
+		{name: "lcm", args: []float64{math.MinInt, 0}, want: 0},
+		{name: "clamp", args: []float64{15, 0, 10}, want: 10},
+		{name: "clamp", args: []float64{-5, 0, 10}, want: 0},
+		{name: "clamp", args: []float64{5, 10, 0}, wantErr: parser.ErrDomain},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fn, err := BuiltinFunction[float64](tt.name)
+			if err != nil {
+				t.Fatalf("BuiltinFunction() err=%v", err)
+			}
+
+			got, err := fn.Fn(tt.args...)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Fn(%v) err=%v wantErr=%v", tt.args, err, tt.wantErr)
+			}
+
+			if tt.wantErr == nil && math.Abs(got-tt.want) > 1e-12 {
+				t.Fatalf("Fn(%v) = %v, want %v", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuiltinFunctionInteger(t *testing.T) {
+	fn, err := BuiltinFunction[int]("sqrt")
+	if err != nil {
+		t.Fatalf("BuiltinFunction() err=%v", err)
+	}
+
+	if got, err := fn.Fn(8); err != nil || got != 2 {
+		t.Fatalf("sqrt(8) = (%d, %v), want 2", got, err)
+	}
+
+	// An infinite result can't be converted to an int, so it's an error in either mode.
+	fn, err = BuiltinFunction[int]("exp")
+	if err != nil {
+		t.Fatalf("BuiltinFunction() err=%v", err)
+	}
+
+	if got, err := fn.Fn(1000); err == nil {
+		t.Fatalf("exp(1000) = %d, want an error", got)
+	}
+
+	// A finite result that doesn't fit in an int is an error too, rather than a wrapped value.
+	if got, err := fn.Fn(100); !errors.Is(err, errOutOfRange) {
+		t.Fatalf("exp(100) = (%d, %v), want %v", got, err, errOutOfRange)
+	}
+
+	fn, err = BuiltinFunction[int]("abs")
+	if err != nil {
+		t.Fatalf("BuiltinFunction() err=%v", err)
+	}
+
+	if got, err := fn.Fn(math.MinInt); !errors.Is(err, errOutOfRange) {
+		t.Fatalf("abs(MinInt) = (%d, %v), want %v", got, err, errOutOfRange)
+	}
+}
+
+func TestLibraryCoversMathFunctions(t *testing.T) {
+	functions, err := Functions[int](Language{Functions: Library()})
+	if err != nil {
+		t.Fatalf("Functions() err=%v", err)
+	}
+
+	if len(functions) != len(MathFunctions) {
+		t.Fatalf("Functions() returned %d functions, want %d", len(functions), len(MathFunctions))
+	}
+
+	if _, err := BuiltinFunction[int]("sqr"); !errors.Is(err, errUnknownImplementation) {
+		t.Fatalf("BuiltinFunction(\"sqr\") err=%v, want errUnknownImplementation", err)
+	}
+}
@@ -0,0 +1,389 @@
+package language
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/lexer"
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/parser"
+)
+
+// builtinTokens maps the operator names used in language files to built-in TokenIds.  Operators with
+// other names are given TokenIds counting up from lexer.FirstCustomToken.
+var builtinTokens = map[string]lexer.TokenId{
+	"Plus":             lexer.Plus,
+	"Minus":            lexer.Minus,
+	"Multiply":         lexer.Multiply,
+	"Divide":           lexer.Divide,
+	"Exponent":         lexer.Exponent,
+	"Factorial":        lexer.Factorial,
+	"Percent":          lexer.Percent,
+	"ImplicitMultiply": lexer.ImplicitMultiply,
+	"Modulo":           lexer.Modulo,
+	"FloorDivide":      lexer.FloorDivide,
+	"BitwiseAnd":       lexer.BitwiseAnd,
+	"BitwiseOr":        lexer.BitwiseOr,
+	"BitwiseXor":       lexer.BitwiseXor,
+	"BitwiseNot":       lexer.BitwiseNot,
+	"ShiftLeft":        lexer.ShiftLeft,
+	"ShiftRight":       lexer.ShiftRight,
+}
+
+// punctuation holds the names a language file uses to spell the tokens that aren't operators.
+var punctuation = map[string]lexer.TokenId{
+	"LParen":    lexer.LParen,
+	"RParen":    lexer.RParen,
+	"Assign":    lexer.Assign,
+	"Semicolon": lexer.Semicolon,
+	"Comma":     lexer.Comma,
+}
+
+var associativities = map[string]parser.Associativity{
+	"":      parser.LeftAssociative,
+	"left":  parser.LeftAssociative,
+	"right": parser.RightAssociative,
+	"none":  parser.NonAssociative,
+}
+
+var fixities = map[string]parser.Fixity{
+	"":        parser.Infix,
+	"infix":   parser.Infix,
+	"postfix": parser.Postfix,
+	"prefix":  parser.Prefix,
+}
+
+// Operations builds the parser.Operations for l's Operators from the built-in implementations.
+func Operations[T parser.Number](l Language) ([]parser.Operation[T], error) {
+	operations := make([]parser.Operation[T], 0, len(l.Operators))
+
+	for _, o := range l.Operators {
+		var op parser.Operation[T]
+
+		if o.Implementation != "" {
+			var err error
+
+			op, err = Builtin[T](o.Implementation)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", o.Description, err)
+			}
+		}
+
+		if o.Prefix != "" {
+			prefix, err := Builtin[T](o.Prefix)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", o.Description, err)
+			}
+
+			op.UnaryFn = prefix.UnaryFn
+		}
+
+		op.Description = o.Description
+		op.TokenId = o.TokenId
+		operations = append(operations, op)
+	}
+
+	return operations, nil
+}
+
+// LoadFile reads a Language from the named JSON file.  See Load.
+func LoadFile(name string) (*Language, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	l, err := Load(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	return l, nil
+}
+
+// Load reads a Language from JSON of the form
+//
+//	{
+//	  "tokens":    [{"value": "+", "operator": "Plus"}, {"value": "(", "operator": "LParen"}, ...],
+//	  "operators": [{"name": "Plus", "implementation": "add"}, ...],
+//	  "groups":    [{"operators": ["Plus", "Minus"], "associativity": "left", "fixity": "infix"}, ...],
+//	  "implicitMultiplication": "Multiply",
+//	  "functions": [{"name": "sqrt", "implementation": "sqrt"}, {"name": "root", "implementation": "sqrt"}, ...],
+//	  "constants": {"VAT_RATE": 0.2}
+//	}
+//
+// Groups are listed in evaluation order, from the most tightly binding.  Associativity is "left", "right"
+// or "none" and fixity is "infix", "postfix" or "prefix"; both may be omitted for left associative infix
+// operators.  An operator in a prefix group names its implementation with "prefix", e.g.
+// {"name": "Minus", "implementation": "subtract", "prefix": "negate"}.  implicitMultiplication is optional.
+// Operator names are free-form, but the names of built-in TokenIds such as "Plus" keep that TokenId.
+// functions is optional and lists the functions of MathFunctions that expressions may call, under any name.
+// constants is optional and maps names such as "VAT_RATE" to values, in addition to MathConstants.  The
+// result is checked with Validate.
+func Load(r io.Reader) (*Language, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+
+	var f file
+	if err := dec.Decode(&f); err != nil {
+		return nil, err
+	}
+
+	var l Language
+
+	ids := map[string]lexer.TokenId{}
+	next := lexer.FirstCustomToken
+
+	for _, o := range f.Operators {
+		if _, ok := ids[o.Name]; ok {
+			return nil, fmt.Errorf("%w: %q", errDuplicateOperator, o.Name)
+		}
+
+		id, ok := builtinTokens[o.Name]
+		if !ok {
+			if next == math.MaxInt8 {
+				return nil, fmt.Errorf("%w: at %q", errTooManyOperators, o.Name)
+			}
+
+			id = next
+			next++
+		}
+
+		ids[o.Name] = id
+		l.Operators = append(l.Operators,
+			Operator{Description: o.Name, TokenId: id, Implementation: o.Implementation, Prefix: o.Prefix})
+	}
+
+	if f.ImplicitMultiplication != "" {
+		id, ok := ids[f.ImplicitMultiplication]
+		if !ok {
+			return nil, fmt.Errorf("%w: implicit multiplication refers to %q", errUnknownOperator, f.ImplicitMultiplication)
+		}
+
+		l.ImplicitMultiplication = id
+	}
+
+	for _, t := range f.Tokens {
+		id, ok := ids[t.Operator]
+		if !ok {
+			id, ok = punctuation[t.Operator]
+		}
+
+		if !ok {
+			return nil, fmt.Errorf("%w: token %q refers to %q", errUnknownOperator, t.Value, t.Operator)
+		}
+
+		l.Tokens = append(l.Tokens, lexer.Token{Id: id, Value: t.Value})
+	}
+
+	for i, g := range f.Groups {
+		var ok bool
+
+		group := parser.OperationGroup{Precedence: parser.Precedence(i)}
+
+		for _, name := range g.Operators {
+			id, found := ids[name]
+			if !found {
+				return nil, fmt.Errorf("%w: group %d refers to %q", errUnknownOperator, i, name)
+			}
+
+			group.Tokens = append(group.Tokens, id)
+		}
+
+		if group.Associativity, ok = associativities[g.Associativity]; !ok {
+			return nil, fmt.Errorf("%w: group %d: associativity %q", errInvalidGroup, i, g.Associativity)
+		}
+
+		if group.Fixity, ok = fixities[g.Fixity]; !ok {
+			return nil, fmt.Errorf("%w: group %d: fixity %q", errInvalidGroup, i, g.Fixity)
+		}
+
+		l.OperationGroups = append(l.OperationGroups, group)
+	}
+
+	for _, fn := range f.Functions {
+		l.Functions = append(l.Functions, Function{Name: fn.Name, Implementation: fn.Implementation})
+	}
+
+	l.Constants = f.Constants
+
+	if err := Validate(l); err != nil {
+		return nil, err
+	}
+
+	return &l, nil
+}
+
+// Validate checks that l is consistent: no token is spelled twice, starts with a digit or contains
+// whitespace, every token and group refers to a defined operator, every operator uses known
+// implementations, is spelled by at least one token (except ImplicitMultiply, which the parser inserts),
+// and belongs to exactly one infix or postfix group if it has an Implementation and exactly one prefix
+// group if it has a Prefix, with each group's fixity matching the number of operands the implementation
+// takes.  Functions and Constants must have valid names, and Constants must be finite and not redefine
+// MathConstants.  Finally the Operations and Functions are checked by parser.NewParser.
+func Validate(l Language) error {
+	operators := map[lexer.TokenId]Operator{}
+	arity := map[string]int{}
+
+	for _, o := range l.Operators {
+		if _, ok := operators[o.TokenId]; ok {
+			return fmt.Errorf("%w: %q uses TokenId %d, which is already in use", errDuplicateOperator, o.Description, o.TokenId)
+		}
+
+		if o.Implementation == "" && o.Prefix == "" {
+			return fmt.Errorf("%w: %q has no implementation", errInvalidOperator, o.Description)
+		}
+
+		for _, name := range []string{o.Implementation, o.Prefix} {
+			if name == "" {
+				continue
+			}
+
+			op, err := Builtin[int](name)
+			if err != nil {
+				return fmt.Errorf("%s: %w", o.Description, err)
+			}
+
+			arity[name] = 2
+			if op.UnaryFn != nil {
+				arity[name] = 1
+			}
+		}
+
+		if o.Prefix != "" && arity[o.Prefix] != 1 {
+			return fmt.Errorf("%w: %q: prefix implementation %q takes two operands", errInvalidOperator, o.Description, o.Prefix)
+		}
+
+		// Prefix and postfix implementations share parser.Operation.UnaryFn.
+		if o.Prefix != "" && o.Implementation != "" && arity[o.Implementation] != 2 {
+			return fmt.Errorf("%w: %q can't be both a prefix and a postfix operator", errInvalidOperator, o.Description)
+		}
+
+		operators[o.TokenId] = o
+	}
+
+	spellings := map[string]bool{}
+	spelled := map[lexer.TokenId]bool{}
+
+	for _, t := range l.Tokens {
+		switch {
+		case t.Value == "":
+			return fmt.Errorf("%w: empty token", errConflictingToken)
+		case t.Value[0] >= '0' && t.Value[0] <= '9':
+			return fmt.Errorf("%w: %q would be read as a number", errConflictingToken, t.Value)
+		case strings.ContainsFunc(t.Value, unicode.IsSpace):
+			// The lexer skips whitespace, so it could never read such a token.
+			return fmt.Errorf("%w: %q contains whitespace", errConflictingToken, t.Value)
+		case spellings[t.Value]:
+			return fmt.Errorf("%w: %q is defined more than once", errConflictingToken, t.Value)
+		}
+
+		_, isOperator := operators[t.Id]
+		if !isOperator && !isPunctuation(t.Id) {
+			return fmt.Errorf("%w: token %q refers to TokenId %d", errUnknownOperator, t.Value, t.Id)
+		}
+
+		spellings[t.Value] = true
+		spelled[t.Id] = true
+	}
+
+	groupCount := map[lexer.TokenId]int{}
+	prefixCount := map[lexer.TokenId]int{}
+
+	for i, g := range l.OperationGroups {
+		if g.Fixity == parser.Postfix && g.Associativity == parser.RightAssociative {
+			return fmt.Errorf("%w: group %d: postfix operators cannot be right associative", errInvalidGroup, i)
+		}
+
+		for _, id := range g.Tokens {
+			o, ok := operators[id]
+			if !ok {
+				return fmt.Errorf("%w: group %d refers to TokenId %d", errUnknownOperator, i, id)
+			}
+
+			if g.Fixity == parser.Prefix {
+				if o.Prefix == "" {
+					return fmt.Errorf("%w: group %d: %q has no prefix implementation", errInvalidGroup, i, o.Description)
+				}
+
+				prefixCount[id]++
+
+				continue
+			}
+
+			if o.Implementation == "" || (g.Fixity == parser.Postfix) != (arity[o.Implementation] == 1) {
+				return fmt.Errorf("%w: group %d: %q implementation %q doesn't suit the group's fixity",
+					errInvalidGroup, i, o.Description, o.Implementation)
+			}
+
+			groupCount[id]++
+		}
+	}
+
+	for _, o := range l.Operators {
+		if o.Implementation != "" && groupCount[o.TokenId] != 1 {
+			return fmt.Errorf("%w: %q is in %d groups", errUngroupedOperator, o.Description, groupCount[o.TokenId])
+		}
+
+		if o.Prefix != "" && prefixCount[o.TokenId] != 1 {
+			return fmt.Errorf("%w: %q is in %d prefix groups", errUngroupedOperator, o.Description, prefixCount[o.TokenId])
+		}
+
+		if !spelled[o.TokenId] && o.TokenId != lexer.ImplicitMultiply {
+			return fmt.Errorf("%w: %q", errUnspelledOperator, o.Description)
+		}
+	}
+
+	for _, f := range l.Functions {
+		if !isName(f.Name) {
+			return fmt.Errorf("%w: %q isn't a valid name", errInvalidFunction, f.Name)
+		}
+	}
+
+	if err := validateConstants(l); err != nil {
+		return err
+	}
+
+	operations, err := Operations[int](l)
+	if err != nil {
+		return err
+	}
+
+	functions, err := Functions[int](l)
+	if err != nil {
+		return err
+	}
+
+	_, err = parser.NewParser(operations, l.OperationGroups,
+		parser.WithImplicitMultiplication(l.ImplicitMultiplication),
+		parser.WithBuiltins(functions),
+	)
+
+	return err
+}
+
+// isName reports whether s would be read by the lexer as a single Identifier.
+func isName(s string) bool {
+	for i, c := range s {
+		if c != '_' && !unicode.IsLetter(c) && (i == 0 || !unicode.IsDigit(c)) {
+			return false
+		}
+	}
+
+	return s != ""
+}
+
+func isPunctuation(id lexer.TokenId) bool {
+	for _, p := range punctuation {
+		if p == id {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,205 @@
+package language
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/lexer"
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/parser"
+)
+
+const wordsLanguage = `{
+  "tokens": [
+    {"value": "plus", "operator": "Plus"},
+    {"value": "times", "operator": "Times"},
+    {"value": "!", "operator": "Factorial"},
+    {"value": "[", "operator": "LParen"},
+    {"value": "]", "operator": "RParen"}
+  ],
+  "operators": [
+    {"name": "Plus", "implementation": "add"},
+    {"name": "Times", "implementation": "multiply"},
+    {"name": "Factorial", "implementation": "factorial"}
+  ],
+  "groups": [
+    {"operators": ["Factorial"], "fixity": "postfix"},
+    {"operators": ["Times"]},
+    {"operators": ["Plus"], "associativity": "left", "fixity": "infix"}
+  ]
+}`
+
+func TestLoad(t *testing.T) {
+	l, err := Load(strings.NewReader(wordsLanguage))
+	if err != nil {
+		t.Fatalf("Load() err=%v", err)
+	}
+
+	if l.Operators[1].TokenId != lexer.FirstCustomToken {
+		t.Fatalf("Times has TokenId %d, want %d", l.Operators[1].TokenId, lexer.FirstCustomToken)
+	}
+
+	if l.OperationGroups[0].Fixity != parser.Postfix || l.OperationGroups[2].Precedence != 2 {
+		t.Fatalf("unexpected groups: %+v", l.OperationGroups)
+	}
+
+	ops, err := Operations[int](*l)
+	if err != nil {
+		t.Fatalf("Operations() err=%v", err)
+	}
+
+	elements, err := lexer.NewLexer("2 times [3! plus 4]", l.Tokens).GetElementList()
+	if err != nil {
+		t.Fatalf("GetElementList() err=%v", err)
+	}
+
+	p, err := parser.NewParser(ops, l.OperationGroups)
+	if err != nil {
+		t.Fatalf("NewParser() err=%v", err)
+	}
+
+	result, err := p.Eval(elements)
+	if err != nil {
+		t.Fatalf("Eval() err=%v", err)
+	}
+
+	if *result != 20 {
+		t.Fatalf("Eval() got=%d want=20", *result)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr error
+	}{
+		{
+			name:    "conflicting tokens",
+			input:   `{"tokens": [{"value": "+", "operator": "Plus"}, {"value": "+", "operator": "Plus"}], "operators": [{"name": "Plus", "implementation": "add"}], "groups": [{"operators": ["Plus"]}]}`,
+			wantErr: errConflictingToken,
+		},
+		{
+			name:    "token that looks like a number",
+			input:   `{"tokens": [{"value": "1", "operator": "Plus"}], "operators": [{"name": "Plus", "implementation": "add"}], "groups": [{"operators": ["Plus"]}]}`,
+			wantErr: errConflictingToken,
+		},
+		{
+			name:    "token containing whitespace",
+			input:   `{"tokens": [{"value": "p lus", "operator": "Plus"}], "operators": [{"name": "Plus", "implementation": "add"}], "groups": [{"operators": ["Plus"]}]}`,
+			wantErr: errConflictingToken,
+		},
+		{
+			name:    "token for an undefined operator",
+			input:   `{"tokens": [{"value": "+", "operator": "Plus"}], "operators": [], "groups": []}`,
+			wantErr: errUnknownOperator,
+		},
+		{
+			name:    "unknown implementation",
+			input:   `{"tokens": [{"value": "+", "operator": "Plus"}], "operators": [{"name": "Plus", "implementation": "concatenate"}], "groups": [{"operators": ["Plus"]}]}`,
+			wantErr: errUnknownImplementation,
+		},
+		{
+			name:    "duplicate operator",
+			input:   `{"tokens": [{"value": "+", "operator": "Plus"}], "operators": [{"name": "Plus", "implementation": "add"}, {"name": "Plus", "implementation": "add"}], "groups": [{"operators": ["Plus"]}]}`,
+			wantErr: errDuplicateOperator,
+		},
+		{
+			name:    "operator missing from groups",
+			input:   `{"tokens": [{"value": "+", "operator": "Plus"}], "operators": [{"name": "Plus", "implementation": "add"}], "groups": []}`,
+			wantErr: errUngroupedOperator,
+		},
+		{
+			name:    "operator without a token",
+			input:   `{"tokens": [], "operators": [{"name": "Plus", "implementation": "add"}], "groups": [{"operators": ["Plus"]}]}`,
+			wantErr: errUnspelledOperator,
+		},
+		{
+			name:    "infix implementation in a postfix group",
+			input:   `{"tokens": [{"value": "+", "operator": "Plus"}], "operators": [{"name": "Plus", "implementation": "add"}], "groups": [{"operators": ["Plus"], "fixity": "postfix"}]}`,
+			wantErr: errInvalidGroup,
+		},
+		{
+			name:    "binary prefix implementation",
+			input:   `{"tokens": [{"value": "-", "operator": "Minus"}], "operators": [{"name": "Minus", "implementation": "subtract", "prefix": "subtract"}], "groups": [{"operators": ["Minus"], "fixity": "prefix"}, {"operators": ["Minus"]}]}`,
+			wantErr: errInvalidOperator,
+		},
+		{
+			name:    "invalid associativity",
+			input:   `{"tokens": [{"value": "+", "operator": "Plus"}], "operators": [{"name": "Plus", "implementation": "add"}], "groups": [{"operators": ["Plus"], "associativity": "sideways"}]}`,
+			wantErr: errInvalidGroup,
+		},
+		{
+			name:    "redefined math constant",
+			input:   `{"tokens": [], "operators": [], "groups": [], "constants": {"pi": 3}}`,
+			wantErr: errInvalidConstant,
+		},
+		{
+			name:    "constant name that isn't an identifier",
+			input:   `{"tokens": [], "operators": [], "groups": [], "constants": {"VAT RATE": 0.2}}`,
+			wantErr: errInvalidConstant,
+		},
+		{
+			name:    "unknown function implementation",
+			input:   `{"tokens": [], "operators": [], "groups": [], "functions": [{"name": "sqrt", "implementation": "squareroot"}]}`,
+			wantErr: errUnknownImplementation,
+		},
+		{
+			name:    "function name that isn't an identifier",
+			input:   `{"tokens": [], "operators": [], "groups": [], "functions": [{"name": "2x", "implementation": "sqrt"}]}`,
+			wantErr: errInvalidFunction,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(strings.NewReader(tt.input))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Load() err=%v want=%v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadConstants(t *testing.T) {
+	l, err := Load(strings.NewReader(`{"tokens": [], "operators": [], "groups": [], "constants": {"VAT_RATE": 0.2}}`))
+	if err != nil {
+		t.Fatalf("Load() err=%v", err)
+	}
+
+	constants, err := Constants[float64](*l)
+	if err != nil {
+		t.Fatalf("Constants() err=%v", err)
+	}
+
+	if constants["VAT_RATE"] != 0.2 || constants["pi"] != math.Pi || len(constants) != len(MathConstants)+1 {
+		t.Fatalf("Constants() = %v", constants)
+	}
+
+	ints, err := Constants[int](*l)
+	if err != nil || ints["e"] != 2 {
+		t.Fatalf("Constants[int]() = (%v, %v), want e = 2", ints, err)
+	}
+}
+
+func TestLoadChecksParserConfiguration(t *testing.T) {
+	// Minus can't be negated after the infix group has treated it as subtraction.
+	input := `{
+	  "tokens": [{"value": "-", "operator": "Minus"}],
+	  "operators": [{"name": "Minus", "implementation": "subtract", "prefix": "negate"}],
+	  "groups": [{"operators": ["Minus"]}, {"operators": ["Minus"], "fixity": "prefix"}]
+	}`
+
+	_, err := Load(strings.NewReader(input))
+	if err == nil {
+		t.Fatalf("Load() err=nil, want an error for a prefix group after the infix group")
+	}
+}
+
+func TestLoadRejectsUnknownFields(t *testing.T) {
+	_, err := Load(strings.NewReader(`{"tokens": [], "operators": [], "groups": [], "precedence": []}`))
+	if err == nil {
+		t.Fatalf("Load() err=nil, want an error for an unknown field")
+	}
+}
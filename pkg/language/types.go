@@ -0,0 +1,74 @@
+package language
+
+import (
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/lexer"
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/parser"
+)
+
+// Language is a complete, mode-independent definition of an expression syntax: how operators are
+// spelled, which built-in implementation each one uses, and their precedence, associativity and fixity.
+// Operations, Functions and Constants build the parser.Operations, parser.BuiltinFunctions and constants
+// for a particular Number type from it.
+type Language struct {
+	Tokens          []lexer.Token
+	Operators       []Operator
+	OperationGroups []parser.OperationGroup
+	// ImplicitMultiplication is the TokenId of the operator inserted between juxtaposed operands,
+	// or lexer.NullToken to reject them.  See parser.WithImplicitMultiplication.
+	ImplicitMultiplication lexer.TokenId
+	// Functions are the built-in functions, such as sqrt, that expressions can call.
+	Functions []Function
+	// Constants are named values such as VAT_RATE, in addition to MathConstants.
+	Constants map[string]float64
+}
+
+// Function names the built-in implementation of a function that expressions call by Name.  See MathFunctions.
+type Function struct {
+	Name           string
+	Implementation string
+}
+
+// Operator names the built-in implementation of the operator identified by TokenId.  Prefix names the
+// implementation used when the operator is in a parser.Prefix group, so that Minus can both subtract
+// and negate.  Either may be empty, but not both.
+type Operator struct {
+	Description    string
+	TokenId        lexer.TokenId
+	Implementation string
+	Prefix         string
+}
+
+// file is the JSON representation of a Language read by Load.
+type file struct {
+	Tokens    []fileToken    `json:"tokens"`
+	Operators []fileOperator `json:"operators"`
+	Groups    []fileGroup    `json:"groups"`
+	// ImplicitMultiplication names the operator inserted between juxtaposed operands.
+	ImplicitMultiplication string `json:"implicitMultiplication"`
+	// Functions lists the built-in functions, which may be renamed.
+	Functions []fileFunction `json:"functions"`
+	// Constants maps names to values.
+	Constants map[string]float64 `json:"constants"`
+}
+
+type fileFunction struct {
+	Name           string `json:"name"`
+	Implementation string `json:"implementation"`
+}
+
+type fileToken struct {
+	Value    string `json:"value"`
+	Operator string `json:"operator"`
+}
+
+type fileOperator struct {
+	Name           string `json:"name"`
+	Implementation string `json:"implementation"`
+	Prefix         string `json:"prefix"`
+}
+
+type fileGroup struct {
+	Operators     []string `json:"operators"`
+	Associativity string   `json:"associativity"`
+	Fixity        string   `json:"fixity"`
+}
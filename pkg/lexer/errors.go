@@ -1,10 +1,23 @@
 package lexer
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrSyntax is wrapped by every error that GetElementList returns for malformed input, such as an unknown
+// operator character or an unterminated comment.
+var ErrSyntax = errors.New("syntax error")
 
 var (
-	errInvalidOperator = errors.New("invalid operator character")
-	errIndexOutOfRange = errors.New("index out of range")
-	errInvalidTokenId = errors.New("invalid TokenId")
-	errUnmatchedParen = errors.New("unmatched parenthesis")
+	errInvalidOperator = fmt.Errorf("%w: invalid operator character", ErrSyntax)
+	errIndexOutOfRange = fmt.Errorf("%w: index out of range", ErrSyntax)
+	errInvalidTokenId  = fmt.Errorf("%w: invalid TokenId", ErrSyntax)
+	errUnmatchedParen  = fmt.Errorf("%w: unmatched parenthesis", ErrSyntax)
+	errMalformedNumber = fmt.Errorf("%w: malformed number literal", ErrSyntax)
+	errUnterminated    = fmt.Errorf("%w: unterminated comment", ErrSyntax)
 )
+
+// errSuperscript is returned for a Lexer whose superscript TokenId has no spelling in its tokens, so that
+// superscripts can't be read however the input is written.
+var errSuperscript = errors.New("superscript TokenId isn't in the tokens")
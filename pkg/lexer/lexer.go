@@ -2,9 +2,12 @@ package lexer
 
 import (
 	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
-func NewLexer(input string, tokens []Token) (expr Lexer) {
+func NewLexer(input string, tokens []Token, opts ...Option) (expr Lexer) {
 	expr.Input = input
 
 	expr.tokens = map[string]TokenId{}
@@ -12,9 +15,92 @@ func NewLexer(input string, tokens []Token) (expr Lexer) {
 		expr.tokens[token.Value] = token.Id
 	}
 
+	for _, opt := range opts {
+		opt(&expr)
+	}
+
+	for _, token := range tokens {
+		if token.Id == expr.superscript {
+			expr.superscriptValue = token.Value
+			break
+		}
+	}
+
 	return expr
 }
 
+// WithIdentifiers makes the lexer produce Identifier elements for names made of letters, digits
+// and underscores that don't start with a digit.  Without it, letters are invalid characters.
+func WithIdentifiers() Option {
+	return func(l *Lexer) {
+		l.identifiers = true
+	}
+}
+
+// DefaultAliases maps characters found in typeset mathematics to the ASCII operators they stand for.
+var DefaultAliases = map[string]string{
+	"×": "*",
+	"·": "*",
+	"÷": "/",
+	"−": "-", // U+2212 MINUS SIGN
+}
+
+// WithAliases lets each key of aliases be used in place of the token spelled by its value, e.g. × for *.
+// Aliases for tokens that don't exist, or that are already tokens themselves, are ignored.
+func WithAliases(aliases map[string]string) Option {
+	return func(l *Lexer) {
+		for alias, value := range aliases {
+			id, ok := l.tokens[value]
+			if _, exists := l.tokens[alias]; ok && !exists {
+				l.tokens[alias] = id
+			}
+		}
+	}
+}
+
+// WithSuperscripts reads superscript digits as an exponent: an element with TokenId tok, spelled as tok is
+// first spelled in the tokens, is inserted before them and they become a Number, so 2³ is read as 2^3 when
+// tok is Exponent.  GetElementList returns an error if tok isn't in the tokens.
+func WithSuperscripts(tok TokenId) Option {
+	return func(l *Lexer) {
+		l.superscript = tok
+	}
+}
+
+// DefaultComments recognises # and // line comments and /* */ block comments.
+var DefaultComments = Comments{Line: []string{"#", "//"}, BlockStart: "/*", BlockEnd: "*/"}
+
+// WithComments makes the lexer skip comments written as described by c.  Comment markers that are also
+// tokens, such as // when it means floor division, are treated as tokens.
+func WithComments(c Comments) Option {
+	return func(l *Lexer) {
+		for _, marker := range c.Line {
+			if _, ok := l.tokens[marker]; !ok && marker != "" {
+				l.comments.Line = append(l.comments.Line, marker)
+			}
+		}
+
+		if _, ok := l.tokens[c.BlockStart]; !ok && c.BlockStart != "" && c.BlockEnd != "" {
+			l.comments.BlockStart, l.comments.BlockEnd = c.BlockStart, c.BlockEnd
+		}
+	}
+}
+
+// WithTrivia keeps comments as Comment elements, so that a formatter can reproduce them.
+func WithTrivia() Option {
+	return func(l *Lexer) {
+		l.trivia = true
+	}
+}
+
+// WithSpans records the position of each element in the input in its Span, so that it can be related to
+// the source text, e.g. when explaining an evaluation.
+func WithSpans() Option {
+	return func(l *Lexer) {
+		l.spans = true
+	}
+}
+
 func (e Element) String() string {
 	return e.TokenValue
 }
@@ -23,44 +109,192 @@ func (e Element) String() string {
 func (l Lexer) GetElementList() (elementList ElementList, err error) {
 	var skip int
 
+	if l.superscript != NullToken && l.superscriptValue == "" {
+		return nil, fmt.Errorf("%w: %d", errSuperscript, l.superscript)
+	}
+
 	elementList = ElementList{}
 	// Iterate over each rune in the string (not each byte)
 	for idx, c := range l.Input {
-		if c == ' ' {
-			continue
-		}
 		// Because we are iterating over a range, we can't increment idx within the for loop.
 		// So instead, if a multiple rune element is processed, skip will be a positive value.
 		if skip > 0 {
 			skip--
 			continue
 		}
-		// Handle operator characters
-		if _, ok := l.tokens[string(c)]; ok {
-			elementList = append(elementList, Element{l.tokens[string(c)], string(c)})
+
+		if unicode.IsSpace(c) {
 			continue
 		}
 
-		if c < '0' || c > '9' {
-			return nil, fmt.Errorf("%w: %c", errInvalidOperator, c)
+		comment, err := l.scanComment(l.Input[idx:])
+		if err != nil {
+			return nil, err
 		}
 
-		// Handle number characters
-		var numStr string
+		if comment != "" {
+			skip = utf8.RuneCountInString(comment) - 1
+			if l.trivia {
+				elementList = append(elementList, l.element(Comment, comment, idx))
+			}
 
-		remaining := l.Input[idx:]
-		for _, remChar := range remaining {
-			if remChar < '0' || remChar > '9' {
-				break
+			continue
+		}
+
+		// Handle operators, which may be spelled with more than one rune.
+		// An identifier that is longer than the operator it starts with wins, so "timestamp" isn't "times" "tamp".
+		token, ok := l.matchToken(l.Input[idx:])
+
+		var name string
+		if l.identifiers && isIdentifierStart(c) {
+			name = scanIdentifier(l.Input[idx:])
+		}
+
+		if ok && len(token.Value) >= len(name) {
+			skip = utf8.RuneCountInString(token.Value) - 1
+			elementList = append(elementList, l.element(token.Id, token.Value, idx))
+
+			continue
+		}
+
+		if name != "" {
+			skip = utf8.RuneCountInString(name) - 1
+			elementList = append(elementList, l.element(Identifier, name, idx))
+
+			continue
+		}
+
+		if l.superscript != NullToken && isSuperscript(c) {
+			digits := scanSuperscript(l.Input[idx:])
+			skip = utf8.RuneCountInString(digits) - 1
+			// The inserted operator shares the span of the digits, since it has no text of its own.
+			number := l.element(Number, normalizeSuperscript(digits), idx)
+			if l.spans {
+				number.Span.End = idx + len(digits)
 			}
 
-			numStr += string(remChar)
+			elementList = append(elementList, Element{Token: l.superscript, TokenValue: l.superscriptValue, Span: number.Span}, number)
+
+			continue
+		}
+
+		if c < '0' || c > '9' {
+			return nil, fmt.Errorf("%w: %c", errInvalidOperator, c)
+		}
+
+		// Handle number literals
+		numStr, width, err := scanNumber(l.Input[idx:])
+		if err != nil {
+			return nil, err
 		}
 		// The range index naturally increments by one rune on each iteration,
-		// so we only need to skip the number by which numStr exceeds 1.
-		skip = len(numStr) - 1
-		elementList = append(elementList, Element{Number, numStr})
+		// so we only need to skip the number by which the literal's width exceeds 1.
+		skip = width - 1
+		// numStr is normalised, e.g. without digit separators, so the Span is set from width.  Number
+		// literals are ASCII, so width is also the number of bytes.
+		number := l.element(Number, numStr, idx)
+		if l.spans {
+			number.Span.End = idx + width
+		}
+
+		elementList = append(elementList, number)
 	}
 
 	return elementList, nil
 }
+
+// element returns an Element for value, which starts at byte offset start of the input.  Its Span is only
+// set if WithSpans was used.
+func (l Lexer) element(tok TokenId, value string, start int) Element {
+	e := Element{Token: tok, TokenValue: value}
+	if l.spans {
+		e.Span = Span{Start: start, End: start + len(value)}
+	}
+
+	return e
+}
+
+func isIdentifierStart(c rune) bool {
+	return c == '_' || unicode.IsLetter(c)
+}
+
+// scanIdentifier returns the identifier at the start of s.
+func scanIdentifier(s string) string {
+	for idx, c := range s {
+		if !isIdentifierStart(c) && !unicode.IsDigit(c) {
+			return s[:idx]
+		}
+	}
+
+	return s
+}
+
+// matchToken returns the longest token that s starts with.
+func (l Lexer) matchToken(s string) (token Token, ok bool) {
+	for value, id := range l.tokens {
+		if len(value) > len(token.Value) && strings.HasPrefix(s, value) {
+			token, ok = Token{Id: id, Value: value}, true
+		}
+	}
+
+	return token, ok
+}
+
+// superscripts maps superscript digits to their values.
+var superscripts = map[rune]rune{
+	'⁰': '0', '¹': '1', '²': '2', '³': '3', '⁴': '4', '⁵': '5', '⁶': '6', '⁷': '7', '⁸': '8', '⁹': '9',
+}
+
+func isSuperscript(c rune) bool {
+	_, ok := superscripts[c]
+
+	return ok
+}
+
+// scanSuperscript returns the superscript digits at the start of s.
+func scanSuperscript(s string) string {
+	for idx, c := range s {
+		if !isSuperscript(c) {
+			return s[:idx]
+		}
+	}
+
+	return s
+}
+
+// normalizeSuperscript converts superscript digits to ordinary ones, e.g. ¹² to 12.
+func normalizeSuperscript(s string) string {
+	var b strings.Builder
+
+	for _, c := range s {
+		b.WriteRune(superscripts[c])
+	}
+
+	return b.String()
+}
+
+// scanComment returns the comment at the start of s, or "" if s doesn't start with a comment.
+// A line comment doesn't include the newline that ends it.
+func (l Lexer) scanComment(s string) (string, error) {
+	for _, marker := range l.comments.Line {
+		if strings.HasPrefix(s, marker) {
+			end := strings.IndexByte(s, '\n')
+			if end < 0 {
+				return s, nil
+			}
+
+			return s[:end], nil
+		}
+	}
+
+	if l.comments.BlockStart != "" && strings.HasPrefix(s, l.comments.BlockStart) {
+		end := strings.Index(s[len(l.comments.BlockStart):], l.comments.BlockEnd)
+		if end < 0 {
+			return "", fmt.Errorf("%w: %s", errUnterminated, l.comments.BlockStart)
+		}
+
+		return s[:len(l.comments.BlockStart)+end+len(l.comments.BlockEnd)], nil
+	}
+
+	return "", nil
+}
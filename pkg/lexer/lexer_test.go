@@ -1,6 +1,7 @@
 package lexer
 
 import (
+	"errors"
 	"reflect"
 	"testing"
 )
@@ -103,6 +104,46 @@ func TestLexer_GetElementList(t *testing.T) {
 			},
 			shouldFail: false,
 		},
+		{
+			name:  "prefixed literals are normalized to decimal",
+			input: "0x1F+0b1010-0o17",
+			expected: ElementList{
+				{Token: Number, TokenValue: "31"},
+				{Token: Plus, TokenValue: "+"},
+				{Token: Number, TokenValue: "10"},
+				{Token: Minus, TokenValue: "-"},
+				{Token: Number, TokenValue: "15"},
+			},
+			shouldFail: false,
+		},
+		{
+			name:  "digit separators",
+			input: "1_000_000*0xFF_FF",
+			expected: ElementList{
+				{Token: Number, TokenValue: "1000000"},
+				{Token: Multiply, TokenValue: "*"},
+				{Token: Number, TokenValue: "65535"},
+			},
+			shouldFail: false,
+		},
+		{
+			name:       "prefix without digits",
+			input:      "0x+1",
+			expected:   nil,
+			shouldFail: true,
+		},
+		{
+			name:       "consecutive underscores",
+			input:      "1__0",
+			expected:   nil,
+			shouldFail: true,
+		},
+		{
+			name:       "invalid digit for base",
+			input:      "0b102",
+			expected:   nil,
+			shouldFail: true,
+		},
 		{
 			name:       "invalid character",
 			input:      "j",
@@ -140,3 +181,319 @@ func TestLexer_GetElementList(t *testing.T) {
 		})
 	}
 }
+
+func TestLexer_GetElementListWithIdentifiers(t *testing.T) {
+	tokens := []Token{
+		{Id: Plus, Value: "+"},
+		{Id: Multiply, Value: "*"},
+		{Id: LParen, Value: "("},
+		{Id: RParen, Value: ")"},
+	}
+
+	tests := []struct {
+		name       string
+		input      string
+		expected   ElementList
+		shouldFail bool
+	}{
+		{
+			name:  "identifiers and numbers",
+			input: "rate * 3x + _tmp2",
+			expected: ElementList{
+				{Token: Identifier, TokenValue: "rate"},
+				{Token: Multiply, TokenValue: "*"},
+				{Token: Number, TokenValue: "3"},
+				{Token: Identifier, TokenValue: "x"},
+				{Token: Plus, TokenValue: "+"},
+				{Token: Identifier, TokenValue: "_tmp2"},
+			},
+			shouldFail: false,
+		},
+		{
+			name:  "non-ASCII letters",
+			input: "(αβ)",
+			expected: ElementList{
+				{Token: LParen, TokenValue: "("},
+				{Token: Identifier, TokenValue: "αβ"},
+				{Token: RParen, TokenValue: ")"},
+			},
+			shouldFail: false,
+		},
+		{
+			name:       "symbols are still invalid",
+			input:      "x+@",
+			expected:   nil,
+			shouldFail: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lex := NewLexer(tt.input, tokens, WithIdentifiers())
+			got, err := lex.GetElementList()
+
+			if (err != nil) != tt.shouldFail {
+				t.Fatalf("GetElementList() err=%v shouldFail=%v", err, tt.shouldFail)
+			}
+			if tt.shouldFail {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Fatalf("GetElementList() got=%#v want=%#v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLexer_GetElementListMultiRuneTokens(t *testing.T) {
+	tokens := []Token{
+		{Id: Multiply, Value: "*"},
+		{Id: Exponent, Value: "**"},
+		{Id: Plus, Value: "plus"},
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		expected ElementList
+	}{
+		{
+			name:  "longest token wins",
+			input: "2**3*4",
+			expected: ElementList{
+				{Token: Number, TokenValue: "2"},
+				{Token: Exponent, TokenValue: "**"},
+				{Token: Number, TokenValue: "3"},
+				{Token: Multiply, TokenValue: "*"},
+				{Token: Number, TokenValue: "4"},
+			},
+		},
+		{
+			name:  "word token",
+			input: "x plus 1",
+			expected: ElementList{
+				{Token: Identifier, TokenValue: "x"},
+				{Token: Plus, TokenValue: "plus"},
+				{Token: Number, TokenValue: "1"},
+			},
+		},
+		{
+			name:  "longer identifier wins over word token",
+			input: "plusses",
+			expected: ElementList{
+				{Token: Identifier, TokenValue: "plusses"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewLexer(tt.input, tokens, WithIdentifiers()).GetElementList()
+			if err != nil {
+				t.Fatalf("GetElementList() err=%v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Fatalf("GetElementList() got=%#v want=%#v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLexer_GetElementListUnicode(t *testing.T) {
+	tokens := []Token{
+		{Id: Minus, Value: "-"},
+		{Id: Multiply, Value: "*"},
+		{Id: Exponent, Value: "^"},
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		opts     []Option
+		expected ElementList
+	}{
+		{
+			name:  "unicode whitespace",
+			input: "2\t*\n3\u00a0*\u20034",
+			expected: ElementList{
+				{Token: Number, TokenValue: "2"},
+				{Token: Multiply, TokenValue: "*"},
+				{Token: Number, TokenValue: "3"},
+				{Token: Multiply, TokenValue: "*"},
+				{Token: Number, TokenValue: "4"},
+			},
+		},
+		{
+			name:  "aliases",
+			input: "2×3−4",
+			opts:  []Option{WithAliases(DefaultAliases)},
+			expected: ElementList{
+				{Token: Number, TokenValue: "2"},
+				{Token: Multiply, TokenValue: "×"},
+				{Token: Number, TokenValue: "3"},
+				{Token: Minus, TokenValue: "−"},
+				{Token: Number, TokenValue: "4"},
+			},
+		},
+		{
+			name:  "superscripts",
+			input: "x²-10¹²",
+			opts:  []Option{WithIdentifiers(), WithSuperscripts(Exponent)},
+			expected: ElementList{
+				{Token: Identifier, TokenValue: "x"},
+				{Token: Exponent, TokenValue: "^"},
+				{Token: Number, TokenValue: "2"},
+				{Token: Minus, TokenValue: "-"},
+				{Token: Number, TokenValue: "10"},
+				{Token: Exponent, TokenValue: "^"},
+				{Token: Number, TokenValue: "12"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewLexer(tt.input, tokens, tt.opts...).GetElementList()
+			if err != nil {
+				t.Fatalf("GetElementList() err=%v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Fatalf("GetElementList() got=%#v want=%#v", got, tt.expected)
+			}
+		})
+	}
+
+	// Without the options, aliases and superscripts are invalid characters.
+	for _, input := range []string{"2×3", "2²"} {
+		if _, err := NewLexer(input, tokens).GetElementList(); err == nil {
+			t.Fatalf("GetElementList(%q) err=nil, want an error", input)
+		}
+	}
+
+	// The operator inserted before superscripts needs a spelling, even if there are none in the input.
+	if _, err := NewLexer("2", []Token{{Id: Plus, Value: "+"}}, WithSuperscripts(Exponent)).GetElementList(); !errors.Is(err, errSuperscript) {
+		t.Fatalf("GetElementList() err=%v, want %v", err, errSuperscript)
+	}
+}
+
+func TestLexer_GetElementListComments(t *testing.T) {
+	tokens := []Token{
+		{Id: Plus, Value: "+"},
+		{Id: Multiply, Value: "*"},
+		{Id: FloorDivide, Value: "//"},
+	}
+
+	tests := []struct {
+		name       string
+		input      string
+		opts       []Option
+		expected   ElementList
+		shouldFail bool
+	}{
+		{
+			name:  "line and block comments are skipped",
+			input: "1 + /* two\n lines */ 2 # the end",
+			opts:  []Option{WithComments(DefaultComments)},
+			expected: ElementList{
+				{Token: Number, TokenValue: "1"},
+				{Token: Plus, TokenValue: "+"},
+				{Token: Number, TokenValue: "2"},
+			},
+		},
+		{
+			name:  "line comment ends at newline",
+			input: "1 # one\n+ 2",
+			opts:  []Option{WithComments(DefaultComments)},
+			expected: ElementList{
+				{Token: Number, TokenValue: "1"},
+				{Token: Plus, TokenValue: "+"},
+				{Token: Number, TokenValue: "2"},
+			},
+		},
+		{
+			name:  "trivia",
+			input: "1 /* x */ * 2 # y",
+			opts:  []Option{WithComments(DefaultComments), WithTrivia()},
+			expected: ElementList{
+				{Token: Number, TokenValue: "1"},
+				{Token: Comment, TokenValue: "/* x */"},
+				{Token: Multiply, TokenValue: "*"},
+				{Token: Number, TokenValue: "2"},
+				{Token: Comment, TokenValue: "# y"},
+			},
+		},
+		{
+			name:  "tokens take priority over comment markers",
+			input: "7 // 2 # floor",
+			opts:  []Option{WithComments(DefaultComments), WithTrivia()},
+			expected: ElementList{
+				{Token: Number, TokenValue: "7"},
+				{Token: FloorDivide, TokenValue: "//"},
+				{Token: Number, TokenValue: "2"},
+				{Token: Comment, TokenValue: "# floor"},
+			},
+		},
+		{
+			name:       "unterminated block comment",
+			input:      "1 /* 2",
+			opts:       []Option{WithComments(DefaultComments)},
+			shouldFail: true,
+		},
+		{
+			name:       "comments disabled by default",
+			input:      "1 # one",
+			shouldFail: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewLexer(tt.input, tokens, tt.opts...).GetElementList()
+
+			if (err != nil) != tt.shouldFail {
+				t.Fatalf("GetElementList() err=%v shouldFail=%v", err, tt.shouldFail)
+			}
+			if tt.shouldFail {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Fatalf("GetElementList() got=%#v want=%#v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLexer_GetElementListSpans(t *testing.T) {
+	tokens := []Token{
+		{Id: Plus, Value: "+"},
+		{Id: Multiply, Value: "*"},
+		{Id: Exponent, Value: "^"},
+	}
+
+	// × is an alias of two bytes, and 1_000 is normalised to 1000 but keeps the span of its source.
+	input := "rate × 1_000 + 2³"
+	expected := ElementList{
+		{Token: Identifier, TokenValue: "rate", Span: Span{Start: 0, End: 4}},
+		{Token: Multiply, TokenValue: "×", Span: Span{Start: 5, End: 7}},
+		{Token: Number, TokenValue: "1000", Span: Span{Start: 8, End: 13}},
+		{Token: Plus, TokenValue: "+", Span: Span{Start: 14, End: 15}},
+		{Token: Number, TokenValue: "2", Span: Span{Start: 16, End: 17}},
+		{Token: Exponent, TokenValue: "^", Span: Span{Start: 17, End: 19}},
+		{Token: Number, TokenValue: "3", Span: Span{Start: 17, End: 19}},
+	}
+
+	l := NewLexer(input, tokens, WithIdentifiers(), WithAliases(DefaultAliases), WithSuperscripts(Exponent), WithSpans())
+
+	got, err := l.GetElementList()
+	if err != nil {
+		t.Fatalf("GetElementList() err=%v", err)
+	}
+
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("GetElementList() = %+v, want %+v", got, expected)
+	}
+
+	if span := got[0].Span.Join(got[2].Span); span != (Span{Start: 0, End: 13}) {
+		t.Fatalf("Join() = %+v, want {0 13}", span)
+	}
+}
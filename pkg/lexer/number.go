@@ -0,0 +1,148 @@
+package lexer
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// scanNumber reads the numeric literal at the start of s and returns its value normalized to a plain
+// decimal string, along with the number of bytes the literal occupies in s.  As well as decimals, which
+// may have a fractional part and an exponent (e.g. 1.5 or 1e6), it accepts the base prefixes 0x, 0b
+// and 0o and single underscores between digits, e.g. "0x1F", "0b1010", "0o17" and "1_000_000".
+func scanNumber(s string) (value string, width int, err error) {
+	base := 10
+
+	if len(s) > 1 && s[0] == '0' {
+		switch s[1] {
+		case 'x', 'X':
+			base = 16
+		case 'b', 'B':
+			base = 2
+		case 'o', 'O':
+			base = 8
+		}
+	}
+
+	if base == 10 {
+		return scanDecimal(s)
+	}
+
+	// Prefixed literals also consume letters so that "0x1G" is reported as a bad digit
+	// rather than being split into a number followed by an invalid operator.
+	width = 2
+	for width < len(s) && (isDecimalChar(s[width]) || isLetter(s[width])) {
+		width++
+	}
+
+	literal := s[:width]
+	body := literal[2:]
+
+	if body == "" {
+		return "", 0, fmt.Errorf("%w: %q: missing digits after base prefix", errMalformedNumber, literal)
+	}
+
+	for i := 0; i < len(body); i++ {
+		if body[i] != '_' && digitValue(body[i]) >= base {
+			return "", 0, fmt.Errorf(
+				"%w: %q: invalid digit %q for base %d", errMalformedNumber, literal, body[i], base)
+		}
+	}
+
+	// An underscore is also allowed directly after the prefix, as in Go.
+	if err := checkUnderscores(literal, strings.TrimPrefix(body, "_")); err != nil {
+		return "", 0, err
+	}
+
+	// big.Int is used so that normalizing never fails on overflow.  Range checking
+	// is left to the parser, exactly as it is for decimal literals.
+	n, _ := new(big.Int).SetString(strings.ReplaceAll(body, "_", ""), base)
+
+	return n.String(), width, nil
+}
+
+// scanDecimal reads a decimal literal with an optional fractional part and exponent.
+// The fractional part and exponent are only consumed if they contain at least one digit,
+// so "2." and "2e" are read as the literal 2 followed by whatever comes next.
+func scanDecimal(s string) (value string, width int, err error) {
+	width = decimalRun(s, 0)
+
+	if width+1 < len(s) && s[width] == '.' && isDigit(s[width+1]) {
+		width = decimalRun(s, width+1)
+	}
+
+	if width+1 < len(s) && (s[width] == 'e' || s[width] == 'E') {
+		exp := width + 1
+		if s[exp] == '+' || s[exp] == '-' {
+			exp++
+		}
+
+		if exp < len(s) && isDigit(s[exp]) {
+			width = decimalRun(s, exp)
+		}
+	}
+
+	literal := s[:width]
+	if err := checkUnderscores(literal, literal); err != nil {
+		return "", 0, err
+	}
+
+	return strings.ReplaceAll(literal, "_", ""), width, nil
+}
+
+// decimalRun returns the index of the first character at or after start that is not a decimal digit or underscore.
+func decimalRun(s string, start int) int {
+	for start < len(s) && isDecimalChar(s[start]) {
+		start++
+	}
+
+	return start
+}
+
+// checkUnderscores returns an error unless every underscore in body sits between two digits.
+// literal is the complete literal and is only used for the error message.
+func checkUnderscores(literal, body string) error {
+	for i := 0; i < len(body); i++ {
+		if body[i] != '_' {
+			continue
+		}
+
+		switch {
+		case i == len(body)-1:
+			return fmt.Errorf("%w: %q: trailing underscore", errMalformedNumber, literal)
+		case body[i+1] == '_':
+			return fmt.Errorf("%w: %q: consecutive underscores", errMalformedNumber, literal)
+		case i == 0 || digitValue(body[i-1]) > 15 || digitValue(body[i+1]) > 15:
+			return fmt.Errorf("%w: %q: underscore must separate digits", errMalformedNumber, literal)
+		}
+	}
+
+	return nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isDecimalChar(c byte) bool {
+	return c == '_' || isDigit(c)
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// digitValue returns the numeric value of a digit character, or a value greater than any
+// supported base if c is not a digit.
+func digitValue(c byte) int {
+	switch {
+	case isDigit(c):
+		return int(c - '0')
+	case c >= 'a' && c <= 'z':
+		return int(c-'a') + 10
+	case c >= 'A' && c <= 'Z':
+		return int(c-'A') + 10
+	default:
+		return 36
+	}
+}
@@ -0,0 +1,60 @@
+package lexer
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestScanNumber(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		wantValue string
+		wantWidth int
+		wantErr   bool
+	}{
+		{name: "decimal", input: "42", wantValue: "42", wantWidth: 2},
+		{name: "decimal stops at operator", input: "12+3", wantValue: "12", wantWidth: 2},
+		{name: "decimal stops at letter", input: "12abc", wantValue: "12", wantWidth: 2},
+		{name: "leading zeros are kept", input: "007", wantValue: "007", wantWidth: 3},
+		{name: "separators", input: "1_000_000", wantValue: "1000000", wantWidth: 9},
+		{name: "hex lower case", input: "0x1f", wantValue: "31", wantWidth: 4},
+		{name: "hex upper case prefix", input: "0XFF)", wantValue: "255", wantWidth: 4},
+		{name: "binary", input: "0b1010", wantValue: "10", wantWidth: 6},
+		{name: "octal", input: "0o17", wantValue: "15", wantWidth: 4},
+		{name: "separator after prefix", input: "0x_1F", wantValue: "31", wantWidth: 5},
+		{name: "larger than int64", input: "0xFFFFFFFFFFFFFFFFFF", wantValue: "4722366482869645213695", wantWidth: 20},
+		{name: "fraction", input: "0.07*2", wantValue: "0.07", wantWidth: 4},
+		{name: "exponent", input: "1e6", wantValue: "1e6", wantWidth: 3},
+		{name: "signed exponent", input: "2.5E-3+1", wantValue: "2.5E-3", wantWidth: 6},
+		{name: "separators in fraction", input: "1_000.000_1", wantValue: "1000.0001", wantWidth: 11},
+		{name: "point without digits is not consumed", input: "2.", wantValue: "2", wantWidth: 1},
+		{name: "exponent without digits is not consumed", input: "2e+x", wantValue: "2", wantWidth: 1},
+		{name: "error - missing hex digits", input: "0x", wantErr: true},
+		{name: "error - underscore before point", input: "1_.5", wantErr: true},
+		{name: "error - missing binary digits", input: "0b+1", wantErr: true},
+		{name: "error - consecutive underscores", input: "1__0", wantErr: true},
+		{name: "error - trailing underscore", input: "10_", wantErr: true},
+		{name: "error - bad binary digit", input: "0b12", wantErr: true},
+		{name: "error - bad octal digit", input: "0o8", wantErr: true},
+		{name: "error - bad hex digit", input: "0x1G", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotValue, gotWidth, err := scanNumber(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("scanNumber() err=%v wantErr=%v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !errors.Is(err, errMalformedNumber) {
+					t.Fatalf("scanNumber() err=%v, want errMalformedNumber", err)
+				}
+				return
+			}
+			if gotValue != tt.wantValue || gotWidth != tt.wantWidth {
+				t.Fatalf("scanNumber() = (%q,%d), want (%q,%d)", gotValue, gotWidth, tt.wantValue, tt.wantWidth)
+			}
+		})
+	}
+}
@@ -7,16 +7,56 @@ type Token struct {
 	Value string
 }
 
-type OperationFn func(int, int) (int, error)
+// OperationFn is the type of a binary operation on ints.
+//
+// Deprecated: operations belong to the parser, which is generic over its number type; use
+// parser.OperationFn[int].
+type OperationFn = func(int, int) (int, error)
+
+// UnaryOperationFn is the type of a unary operation on ints.
+//
+// Deprecated: use parser.UnaryOperationFn[int].
+type UnaryOperationFn = func(int) (int, error)
 
 type Lexer struct {
-	Input  string
-	tokens map[string]TokenId
+	Input       string
+	tokens      map[string]TokenId
+	identifiers bool
+	// superscript is the TokenId of the operator inserted before superscript digits, or NullToken.
+	superscript TokenId
+	// superscriptValue is the first spelling of superscript in the tokens, which the inserted operator is given.
+	superscriptValue string
+	comments         Comments
+	// trivia keeps comments in the ElementList as Comment elements instead of discarding them.
+	trivia bool
+	// spans records the position of each Element in Input.
+	spans bool
+}
+
+// Comments describes the comment syntax recognised by the lexer.  Line comments run from any of the
+// Line markers to the end of the line; block comments run from BlockStart to the next BlockEnd.
+type Comments struct {
+	Line       []string
+	BlockStart string
+	BlockEnd   string
 }
 
+// Option configures optional Lexer behaviour.
+type Option func(*Lexer)
+
 type Element struct {
 	Token      TokenId
 	TokenValue string
+	// Span is the part of the input the Element was read from, or the zero Span if the lexer wasn't
+	// asked to record spans.  The parser gives the Elements it creates the Span of what they replace.
+	Span Span
+}
+
+// Span is a range of byte offsets in the input, from Start up to but not including End.  The zero
+// Span means that the position is unknown.
+type Span struct {
+	Start int
+	End   int
 }
 
 const (
@@ -29,6 +69,37 @@ const (
 	LParen
 	RParen
 	Exponent
+	Factorial
+	Percent
+	Identifier       // Identifier is a name such as x or rate, only produced when WithIdentifiers is used
+	ImplicitMultiply // ImplicitMultiply is never produced by the lexer; the parser inserts it between juxtaposed operands
+	Modulo
+	FloorDivide
+	BitwiseAnd
+	BitwiseOr
+	BitwiseXor
+	BitwiseNot
+	ShiftLeft
+	ShiftRight
+	Assign    // Assign separates the name and the expression in a statement such as x = 1
+	Semicolon // Semicolon separates statements
+	Comma     // Comma separates the parameters and arguments of functions
+	Comment   // Comment is only produced when WithTrivia is used; Eval ignores it
+
+	// FirstCustomToken is the lowest TokenId that is free for operators that aren't built in.
+	FirstCustomToken
 )
 
 type ElementList []Element
+
+// Join returns the smallest Span that covers both s and t.  If either is the zero Span, the other is returned.
+func (s Span) Join(t Span) Span {
+	switch {
+	case s == Span{}:
+		return t
+	case t == Span{}:
+		return s
+	default:
+		return Span{Start: min(s.Start, t.Start), End: max(s.End, t.End)}
+	}
+}
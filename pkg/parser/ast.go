@@ -0,0 +1,461 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/lexer"
+)
+
+// NodeType is the kind of expression a Node represents.
+type NodeType int8
+
+const (
+	NumberNode     NodeType = iota // NumberNode is a Number literal
+	IdentifierNode                 // IdentifierNode is a variable or constant, resolved when the expression is evaluated
+	InfixNode                      // InfixNode applies an Operation to its two Operands
+	PrefixNode                     // PrefixNode applies an Operation to the Operand that follows it
+	PostfixNode                    // PostfixNode applies an Operation to the Operand that precedes it
+	CallNode                       // CallNode calls a Function or BuiltinFunction with its Operands as arguments
+)
+
+var nodeTypeNames = map[NodeType]string{
+	NumberNode:     "number",
+	IdentifierNode: "identifier",
+	InfixNode:      "infix",
+	PrefixNode:     "prefix",
+	PostfixNode:    "postfix",
+	CallNode:       "call",
+}
+
+func (t NodeType) String() string {
+	if name, ok := nodeTypeNames[t]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("NodeType(%d)", int8(t))
+}
+
+// fixity returns the Fixity of the OperationGroup that an operator Node of type t belongs to.
+func (t NodeType) fixity() Fixity {
+	switch t {
+	case PrefixNode:
+		return Prefix
+	case PostfixNode:
+		return Postfix
+	default:
+		return Infix
+	}
+}
+
+// Node is a parsed expression.  Token is the TokenId of the operator of an InfixNode, PrefixNode or
+// PostfixNode.  Value is the literal of a NumberNode, the name of an IdentifierNode or CallNode, or the
+// operator as it was written, which is empty for one inserted by implicit multiplication.  Span is the part
+// of the source the Node was parsed from, not including any parentheses around it, if the lexer recorded spans.
+// Comments are the comments that Parse attached to the Node.
+type Node struct {
+	Type     NodeType
+	Token    lexer.TokenId
+	Value    string
+	Operands []Node
+	Span     lexer.Span
+	Comments []Comment
+}
+
+// Comment is a comment kept by Parse, so that Printer can write it back out.  Text is the comment as it
+// was written, including its markers.  A Leading comment is written before the Node it is attached to,
+// and the others after it.
+type Comment struct {
+	Text    string
+	Leading bool
+}
+
+// Parse parses e, an expression such as Eval accepts, into a Node without evaluating it.  Operators are
+// grouped exactly as Eval would group them, and calls are recognised using the Parser's Environment and
+// BuiltinFunctions, but identifiers aren't resolved.  Comment elements, such as the lexer produces with
+// WithTrivia, are attached to the Node whose Span ends closest before them, or to the outermost of several
+// that end there, or else to the root as Leading comments.  Without spans they all follow the root.
+func (p Parser[T]) Parse(e lexer.ElementList) (Node, error) {
+	if err := p.checkNesting(e); err != nil {
+		return Node{}, err
+	}
+
+	b := builder[T]{Parser: p}
+
+	// Number literals are replaced by references to Nodes before the first reduction, so that every Number
+	// element the builder sees is a reference.
+	elementList := make(lexer.ElementList, 0, len(e))
+
+	var comments lexer.ElementList
+
+	for _, element := range e {
+		switch element.Token {
+		case lexer.Comment:
+			comments = append(comments, element)
+			continue
+		case lexer.Number:
+			element = b.ref(Node{Type: NumberNode, Value: element.TokenValue, Span: element.Span})
+		}
+
+		elementList = append(elementList, element)
+	}
+
+	root, err := b.parse(elementList)
+	if err != nil {
+		return Node{}, err
+	}
+
+	n := b.node(root)
+	attachComments(&n, comments)
+
+	return n, nil
+}
+
+// attachComments attaches each of the Comment elements comments to n or one of its descendants, as Parse
+// describes.
+func attachComments(n *Node, comments lexer.ElementList) {
+	for _, c := range comments {
+		var nearest *Node
+
+		// The Nodes are visited outermost first, so the first of several that end at the same place is kept.
+		stack := []*Node{n}
+		for len(stack) > 0 {
+			m := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+
+			if m.Span.End <= c.Span.Start && (nearest == nil || m.Span.End > nearest.Span.End) {
+				nearest = m
+			}
+
+			for i := range m.Operands {
+				stack = append(stack, &m.Operands[len(m.Operands)-1-i])
+			}
+		}
+
+		if nearest == nil {
+			n.Comments = append(n.Comments, Comment{Text: c.TokenValue, Leading: true})
+		} else {
+			nearest.Comments = append(nearest.Comments, Comment{Text: c.TokenValue})
+		}
+	}
+}
+
+// builder reduces an ElementList to a Node in the same order that eval reduces it to a value.  In place of a
+// value, each Number element holds the index in nodes of the Node it stands for.
+type builder[T Number] struct {
+	Parser[T]
+	nodes []Node
+}
+
+// ref adds n to the builder and returns a Number element referring to it.
+func (b *builder[T]) ref(n Node) lexer.Element {
+	b.nodes = append(b.nodes, n)
+
+	return lexer.Element{Token: lexer.Number, TokenValue: strconv.Itoa(len(b.nodes) - 1), Span: n.Span}
+}
+
+// node returns the Node that the Number element e refers to.
+func (b *builder[T]) node(e lexer.Element) Node {
+	idx, _ := strconv.Atoi(e.TokenValue)
+
+	return b.nodes[idx]
+}
+
+// parse reduces elementList to a single element referring to its Node.  The steps are those of eval.
+func (b *builder[T]) parse(e lexer.ElementList) (lexer.Element, error) {
+	elementList, err := b.parseCalls(slices.Clone(e))
+	if err != nil {
+		return lexer.Element{}, err
+	}
+
+	elementList = b.insertImplicitMultiplication(elementList)
+
+	for i, element := range elementList {
+		if element.Token == lexer.Identifier {
+			elementList[i] = b.ref(Node{Type: IdentifierNode, Value: element.TokenValue, Span: element.Span})
+		}
+	}
+
+	elementList, err = b.parseParen(elementList)
+	if err != nil {
+		return lexer.Element{}, err
+	}
+
+	r := reductions{infix: b.parseInfix, postfix: b.parsePostfix, prefix: b.parsePrefix}
+	for _, group := range b.OperationGroups {
+		elementList, err = b.reduce(elementList, group, r)
+		if err != nil {
+			return lexer.Element{}, err
+		}
+	}
+
+	if len(elementList) != 1 || elementList[0].Token != lexer.Number {
+		return lexer.Element{}, fmt.Errorf("%w: %v", errInvalidExpression, elementList)
+	}
+
+	return elementList[0], nil
+}
+
+// parseCalls replaces every call to a Function or BuiltinFunction with a reference to a CallNode, kept in
+// parentheses as evalCalls keeps its results.
+func (b *builder[T]) parseCalls(elementList lexer.ElementList) (lexer.ElementList, error) {
+	env, _ := b.environment.(*Environment[T])
+
+	for i := 0; i < len(elementList)-1; i++ {
+		if elementList[i].Token != lexer.Identifier || elementList[i+1].Token != lexer.LParen {
+			continue
+		}
+
+		name := elementList[i].TokenValue
+
+		var ok bool
+		if env != nil {
+			_, ok = env.Function(name)
+		}
+
+		if !ok && b.builtin(name) == nil {
+			continue
+		}
+
+		rParenIdx, err := elementList.FindRParen(i + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		call := Node{Type: CallNode, Value: name, Span: spanOf(elementList[i : rParenIdx+1])}
+
+		for _, part := range splitArguments(elementList[i+2 : rParenIdx]) {
+			arg, err := b.parse(part)
+			if err != nil {
+				return nil, err
+			}
+
+			call.Operands = append(call.Operands, b.node(arg))
+		}
+
+		elementList = slices.Replace(elementList, i, rParenIdx+1,
+			lexer.Element{Token: lexer.LParen, TokenValue: "("},
+			b.ref(call),
+			lexer.Element{Token: lexer.RParen, TokenValue: ")"},
+		)
+		i += 2
+	}
+
+	return elementList, nil
+}
+
+// parseParen replaces each parenthetical expression with a reference to its Node.
+func (b *builder[T]) parseParen(elementList lexer.ElementList) (lexer.ElementList, error) {
+	for {
+		lParenIdx, tf := elementList.FindLParen()
+		if !tf {
+			break
+		}
+
+		rParenIdx, err := elementList.FindRParen(lParenIdx)
+		if err != nil {
+			return nil, err
+		}
+
+		inner, err := b.parse(elementList[lParenIdx+1 : rParenIdx])
+		if err != nil {
+			return nil, err
+		}
+
+		// The reference covers the parentheses, so that the Span of an enclosing Node does too.
+		inner.Span = spanOf(elementList[lParenIdx : rParenIdx+1])
+		elementList = slices.Replace(elementList, lParenIdx, rParenIdx+1, inner)
+	}
+
+	return elementList, nil
+}
+
+// parseInfix replaces the infix operator at idx and its operands with a reference to an InfixNode.
+func (b *builder[T]) parseInfix(idx int, elementList lexer.ElementList) (lexer.ElementList, error) {
+	subExpr, err := b.getOperatorElements(idx, elementList)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := b.infixOperation(subExpr[1].Token); err != nil {
+		return nil, err
+	}
+
+	n := b.operator(InfixNode, subExpr[1], subExpr[0], subExpr[2])
+
+	return slices.Replace(elementList, idx-1, idx+2, b.ref(n)), nil
+}
+
+// parsePostfix replaces the postfix operator at idx and its operand with a reference to a PostfixNode.
+func (b *builder[T]) parsePostfix(idx int, elementList lexer.ElementList) (lexer.ElementList, error) {
+	if _, err := b.unaryOperation(idx, elementList, Postfix); err != nil {
+		return nil, err
+	}
+
+	n := b.operator(PostfixNode, elementList[idx], elementList[idx-1])
+
+	return slices.Replace(elementList, idx-1, idx+1, b.ref(n)), nil
+}
+
+// parsePrefix replaces the prefix operator at idx and its operand with a reference to a PrefixNode.
+func (b *builder[T]) parsePrefix(idx int, elementList lexer.ElementList) (lexer.ElementList, error) {
+	if _, err := b.unaryOperation(idx, elementList, Prefix); err != nil {
+		return nil, err
+	}
+
+	n := b.operator(PrefixNode, elementList[idx], elementList[idx+1])
+
+	return slices.Replace(elementList, idx, idx+2, b.ref(n)), nil
+}
+
+// operator returns a Node of type t for the operator element op applied to the Nodes that operands refer to.
+func (b *builder[T]) operator(t NodeType, op lexer.Element, operands ...lexer.Element) Node {
+	n := Node{Type: t, Token: op.Token, Value: op.TokenValue, Span: op.Span}
+
+	for _, operand := range operands {
+		n.Operands = append(n.Operands, b.node(operand))
+		n.Span = n.Span.Join(operand.Span)
+	}
+
+	return n
+}
+
+// EvalNode evaluates n, a Node returned by Parse, ParseRPN, ParsePolish or UnmarshalNode, without turning it
+// back into elements, so that the Node is never parsed again.  Identifiers and calls are resolved and Limits
+// checked as Eval would, except that MaxTokens limits the number of Nodes.  The Node isn't evaluated
+// recursively, so MaxDepth only applies to the Bodies of the Functions it calls.
+func (p Parser[T]) EvalNode(n Node) (*T, error) {
+	return p.EvalNodeContext(context.Background(), n)
+}
+
+// EvalNodeContext is EvalNode with a Context, which stops the evaluation with ctx.Err() if it is cancelled.
+func (p Parser[T]) EvalNodeContext(ctx context.Context, n Node) (*T, error) {
+	nodes := postOrder(n)
+	if p.limits.MaxTokens > 0 && len(nodes) > p.limits.MaxTokens {
+		return nil, fmt.Errorf("%w: %w: %d nodes, more than %d", ErrEvaluation, ErrLimitExceeded, len(nodes), p.limits.MaxTokens)
+	}
+
+	p.budget = &budget{ctx: ctx}
+
+	// Each Node's operands are evaluated before it, so they are the values on top of the stack.
+	var values []T
+
+	for _, n := range nodes {
+		if err := checkOperands(n); err != nil {
+			return nil, err
+		}
+
+		operands := slices.Clone(values[len(values)-len(n.Operands):])
+		values = values[:len(values)-len(n.Operands)]
+
+		v, err := p.evalNode(n, operands)
+		if err != nil {
+			return nil, err
+		}
+
+		values = append(values, v)
+	}
+
+	return &values[0], nil
+}
+
+// postOrder returns n and its operands, at every depth, with each Node's operands before it.
+func postOrder(n Node) []Node {
+	var nodes []Node
+
+	// Visiting each Node before its operands, with the last operand first, gives the reverse of the post-order.
+	stack := []Node{n}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		nodes = append(nodes, n)
+		stack = append(stack, n.Operands...)
+	}
+
+	slices.Reverse(nodes)
+
+	return nodes
+}
+
+// evalNode returns the value of n, whose Operands have the values operands.
+func (p Parser[T]) evalNode(n Node, operands []T) (T, error) {
+	switch n.Type {
+	case NumberNode:
+		return parseNumber[T](n.Value)
+	case IdentifierNode:
+		return p.value(n.Value)
+	case CallNode:
+		fn, builtin, ok := p.function(n.Value)
+		if !ok {
+			return 0, fmt.Errorf("%w: %w: %s", ErrEvaluation, errUndefinedIdentifier, n.Value)
+		}
+
+		v, err := p.invoke(n.Value, fn, builtin, operands)
+		if err != nil {
+			return 0, err
+		}
+
+		p.record(Step[T]{Description: n.Value, Expression: callExpression(n.Value, operands), Operands: operands, Result: v, Span: n.Span})
+
+		return v, nil
+	}
+
+	op, err := p.nodeOperation(n)
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		v    T
+		expr string
+	)
+
+	switch n.Type {
+	case InfixNode:
+		v, err = p.applyFn(op, operands[0], operands[1])
+		expr = infixExpression(lexer.ElementList{
+			{TokenValue: formatNumber(operands[0])}, {TokenValue: n.Value}, {TokenValue: formatNumber(operands[1])},
+		})
+	case PrefixNode:
+		v, err = p.applyUnaryFn(op, operands[0])
+		expr = n.Value + formatNumber(operands[0])
+	default:
+		v, err = p.applyUnaryFn(op, operands[0])
+		expr = formatNumber(operands[0]) + n.Value
+	}
+
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrEvaluation, err)
+	}
+
+	p.record(Step[T]{Description: op.Description, Expression: expr, Operands: operands, Result: v, Span: n.Span})
+
+	return v, nil
+}
+
+// nodeOperation returns the Operation for the operator Node n, which must be in an OperationGroup of the
+// Fixity its type implies and have the function that Fixity needs.
+func (p Parser[T]) nodeOperation(n Node) (*Operation[T], error) {
+	fixity := n.Type.fixity()
+	if p.groupOf(n.Token, fixity) < 0 {
+		return nil, fmt.Errorf("%w: TokenId %d isn't in a %s OperationGroup", errInvalidOperation, n.Token, n.Type)
+	}
+
+	if fixity == Infix {
+		return p.infixOperation(n.Token)
+	}
+
+	op, err := p.getOperationByTokenId(n.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	if op.UnaryFn == nil {
+		return nil, fmt.Errorf("%w: %s cannot be used as a %s operator", errInvalidOperation, op.Description, n.Type)
+	}
+
+	return op, nil
+}
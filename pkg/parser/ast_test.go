@@ -0,0 +1,263 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/lexer"
+)
+
+func TestParser_Parse(t *testing.T) {
+	_, loose, implicit := printerParsers()
+
+	num := func(v string) Node { return Node{Type: NumberNode, Value: v} }
+	id := func(v string) Node { return Node{Type: IdentifierNode, Value: v} }
+	infix := func(tok lexer.TokenId, v string, a, b Node) Node {
+		return Node{Type: InfixNode, Token: tok, Value: v, Operands: []Node{a, b}}
+	}
+
+	tests := []struct {
+		name   string
+		parser Parser[int]
+		input  string
+		want   Node
+	}{
+		{name: "number", parser: loose, input: "42", want: num("42")},
+		{name: "precedence", parser: loose, input: "1+2*3",
+			want: infix(lexer.Plus, "+", num("1"), infix(lexer.Multiply, "*", num("2"), num("3")))},
+		{name: "parentheses", parser: loose, input: "(1+2)*3",
+			want: infix(lexer.Multiply, "*", infix(lexer.Plus, "+", num("1"), num("2")), num("3"))},
+		{name: "right associative", parser: loose, input: "2^3^2",
+			want: infix(lexer.Exponent, "^", num("2"), infix(lexer.Exponent, "^", num("3"), num("2")))},
+		{name: "prefix and postfix", parser: loose, input: "-3!",
+			want: Node{Type: PrefixNode, Token: lexer.Minus, Value: "-", Operands: []Node{
+				{Type: PostfixNode, Token: lexer.Factorial, Value: "!", Operands: []Node{num("3")}},
+			}}},
+		{name: "implicit multiplication and call", parser: implicit, input: "2x max(x, 1)",
+			want: infix(lexer.ImplicitMultiply, "", infix(lexer.ImplicitMultiply, "", num("2"), id("x")),
+				Node{Type: CallNode, Value: "max", Operands: []Node{id("x"), num("1")}})},
+		{name: "call without arguments", parser: implicit, input: "max()",
+			want: Node{Type: CallNode, Value: "max"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.parser.Parse(lex(t, tt.input))
+			if err != nil {
+				t.Fatalf("Parse() err=%v", err)
+			}
+
+			if got = withoutSpans(got); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Parse() got=%v want=%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParser_ParseSpans(t *testing.T) {
+	_, loose, _ := printerParsers()
+
+	e, err := lexer.NewLexer("(1 + 2) * 30", printerTokens, lexer.WithSpans()).GetElementList()
+	if err != nil {
+		t.Fatalf("GetElementList() err=%v", err)
+	}
+
+	n, err := loose.Parse(e)
+	if err != nil {
+		t.Fatalf("Parse() err=%v", err)
+	}
+
+	// A Node's Span doesn't include its own parentheses, but does include those of its Operands.
+	got := []lexer.Span{n.Span, n.Operands[0].Span, n.Operands[0].Operands[1].Span, n.Operands[1].Span}
+	want := []lexer.Span{{Start: 0, End: 12}, {Start: 1, End: 6}, {Start: 5, End: 6}, {Start: 10, End: 12}}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Parse() spans got=%v want=%v", got, want)
+	}
+}
+
+func TestParser_ParseComments(t *testing.T) {
+	_, loose, _ := printerParsers()
+	opts := []lexer.Option{lexer.WithSpans(), lexer.WithTrivia(), lexer.WithComments(lexer.DefaultComments)}
+
+	e, err := lexer.NewLexer("# sum\n(1 + 2 /* two */) * 30 # total", printerTokens, opts...).GetElementList()
+	if err != nil {
+		t.Fatalf("GetElementList() err=%v", err)
+	}
+
+	n, err := loose.Parse(e)
+	if err != nil {
+		t.Fatalf("Parse() err=%v", err)
+	}
+
+	// Each comment follows the outermost Node that ends closest before it, or leads the root if none does.
+	got := [][]Comment{n.Comments, n.Operands[0].Comments}
+	want := [][]Comment{{{Text: "# sum", Leading: true}, {Text: "# total"}}, {{Text: "/* two */"}}}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Parse() comments got=%v want=%v", got, want)
+	}
+}
+
+func TestParser_ParseErrors(t *testing.T) {
+	_, loose, _ := printerParsers()
+
+	tests := []struct {
+		name     string
+		elements lexer.ElementList
+		// want is nil if any error will do.
+		want error
+	}{
+		{name: "empty", elements: lexer.ElementList{}, want: errInvalidExpression},
+		{name: "missing operand", elements: script("1 +"), want: errIndexOutOfRange},
+		{name: "unbalanced", elements: script("( 1")},
+		{name: "too deep", elements: script(strings.Repeat("( ", DefaultMaxDepth+1) + "1"), want: ErrLimitExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := loose.Parse(tt.elements); err == nil || tt.want != nil && !errors.Is(err, tt.want) {
+				t.Fatalf("Parse() err=%v want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// TestParser_EvalNode checks that evaluating random trees gives the same results as evaluating their elements.
+func TestParser_EvalNode(t *testing.T) {
+	tight, loose, implicit := printerParsers()
+	r := rand.New(rand.NewPCG(7, 8))
+
+	env := NewEnvironment[int]()
+	env.Set("x", 3)
+
+	for _, p := range []Parser[int]{tight, loose, implicit} {
+		// The test Operations don't check for overflow, so without a bound on magnitude 9!! would take forever.
+		p.environment, p.limits = env, Limits{MaxMagnitude: 1000}
+		pr := NewPrinter(printerTokens, p.OperationGroups)
+
+		for range 1000 {
+			n := randomNode(r, p, 4)
+
+			elements, err := pr.Elements(n)
+			if err != nil {
+				t.Fatalf("Elements(%v) err=%v", n, err)
+			}
+
+			want, wantErr := p.Eval(elements)
+
+			got, err := p.EvalNode(n)
+			if (err != nil) != (wantErr != nil) || err == nil && *got != *want {
+				t.Fatalf("EvalNode(%v) = (%v, %v), want (%v, %v)", n, got, err, want, wantErr)
+			}
+		}
+	}
+}
+
+func TestParser_EvalNodeTrace(t *testing.T) {
+	_, _, implicit := printerParsers()
+
+	var want, got Trace[int]
+
+	input := "2(3 + 4) - max(1, 5)!"
+	implicit.trace = &want
+	if _, err := implicit.Eval(lex(t, input)); err != nil {
+		t.Fatalf("Eval() err=%v", err)
+	}
+
+	n, err := implicit.Parse(lex(t, input))
+	if err != nil {
+		t.Fatalf("Parse() err=%v", err)
+	}
+
+	implicit.trace = &got
+	if _, err := implicit.EvalNode(n); err != nil {
+		t.Fatalf("EvalNode() err=%v", err)
+	}
+
+	// Eval calls functions before applying any Operation, and the span of a Step for parentheses includes
+	// them while a Node's doesn't, so only the Steps themselves are compared.
+	for _, trace := range []*Trace[int]{&want, &got} {
+		for i := range trace.Steps {
+			trace.Steps[i].Span = lexer.Span{}
+		}
+
+		slices.SortFunc(trace.Steps, func(a, b Step[int]) int { return strings.Compare(a.Expression, b.Expression) })
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("EvalNode() steps=%v want %v", got.Steps, want.Steps)
+	}
+}
+
+func TestParser_EvalNodeErrors(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, loose, _ := printerParsers()
+	one := Node{Type: NumberNode, Value: "1"}
+	sum := Node{Type: InfixNode, Token: lexer.Plus, Value: "+", Operands: []Node{one, one}}
+
+	tests := []struct {
+		name    string
+		n       Node
+		limits  Limits
+		ctx     context.Context
+		wantErr error
+	}{
+		{name: "undefined identifier", n: Node{Type: IdentifierNode, Value: "x"}, wantErr: errUndefinedIdentifier},
+		{name: "undefined function", n: Node{Type: CallNode, Value: "f", Operands: []Node{one}}, wantErr: errUndefinedIdentifier},
+		{name: "too many nodes", n: sum, limits: Limits{MaxTokens: 2}, wantErr: ErrLimitExceeded},
+		{name: "too many operations", n: Node{Type: InfixNode, Token: lexer.Plus, Operands: []Node{sum, one}},
+			limits: Limits{MaxOperations: 1}, wantErr: ErrLimitExceeded},
+		{name: "too large", n: sum, limits: Limits{MaxMagnitude: 1}, wantErr: ErrLimitExceeded},
+		{name: "cancelled", n: sum, ctx: cancelled, wantErr: context.Canceled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := tt.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+
+			p := loose
+			p.limits = tt.limits
+
+			_, err := p.EvalNodeContext(ctx, tt.n)
+			if !errors.Is(err, tt.wantErr) || !errors.Is(err, ErrEvaluation) {
+				t.Fatalf("EvalNodeContext() err=%v wantErr=%v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Nodes that couldn't have been parsed are rejected, though not as evaluation errors.
+	for _, n := range []Node{
+		{Type: InfixNode, Token: lexer.Plus, Operands: []Node{one}},
+		{Type: PrefixNode, Token: lexer.Plus, Operands: []Node{one}},
+		{Type: PostfixNode, Token: lexer.Minus, Operands: []Node{one}},
+	} {
+		if _, err := loose.EvalNode(n); err == nil {
+			t.Fatalf("EvalNode(%v) err=nil", n)
+		}
+	}
+}
+
+// TestParser_EvalNodeLong checks that a long chain, far deeper than MaxDepth, is evaluated without recursion.
+func TestParser_EvalNodeLong(t *testing.T) {
+	_, loose, _ := printerParsers()
+
+	n := Node{Type: NumberNode, Value: "1"}
+	for range 1_000_000 {
+		n = Node{Type: InfixNode, Token: lexer.Plus, Value: "+", Operands: []Node{n, {Type: NumberNode, Value: "1"}}}
+	}
+
+	if got, err := loose.EvalNode(n); err != nil || *got != 1_000_001 {
+		t.Fatalf("EvalNode() = (%v, %v), want 1000001", got, err)
+	}
+}
@@ -0,0 +1,128 @@
+package parser
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/lexer"
+)
+
+// Environment holds the variables that Identifier elements are resolved against, and the Functions
+// that can be called.  The Environment a Function is called in holds its parameters and has the
+// Environment returned by NewEnvironment as its parent, so a Function can see global variables but
+// not the parameters of the Function that called it.
+type Environment[T Number] struct {
+	variables map[string]T
+	functions map[string]Function
+	parent    *Environment[T]
+}
+
+// Function is a user-defined function such as f(x, y) = x^2 + y.  Calling it evaluates Body with each
+// of Params bound to the corresponding argument.
+type Function struct {
+	Params []string
+	Body   lexer.ElementList
+}
+
+func NewEnvironment[T Number]() *Environment[T] {
+	return &Environment[T]{variables: map[string]T{}, functions: map[string]Function{}}
+}
+
+// Get returns the value of the named variable and whether it is defined.
+func (env *Environment[T]) Get(name string) (T, bool) {
+	for ; env != nil; env = env.parent {
+		if v, ok := env.variables[name]; ok {
+			return v, true
+		}
+	}
+
+	return 0, false
+}
+
+// Define defines the named Function, replacing any previous definition.
+func (env *Environment[T]) Define(name string, fn Function) {
+	env.global().functions[name] = fn
+}
+
+// Function returns the named Function and whether it is defined.
+func (env *Environment[T]) Function(name string) (Function, bool) {
+	fn, ok := env.global().functions[name]
+	return fn, ok
+}
+
+// FunctionNames returns the names of all defined Functions in sorted order.
+func (env *Environment[T]) FunctionNames() []string {
+	return slices.Sorted(maps.Keys(env.global().functions))
+}
+
+// global returns the Environment at the root of env's parents.
+func (env *Environment[T]) global() *Environment[T] {
+	for env.parent != nil {
+		env = env.parent
+	}
+
+	return env
+}
+
+// call returns a new Environment for a call to a Function, with its parameters bound to args.
+func (env *Environment[T]) call(params []string, args []T) *Environment[T] {
+	local := &Environment[T]{variables: map[string]T{}, parent: env.global()}
+	for i, name := range params {
+		local.variables[name] = args[i]
+	}
+
+	return local
+}
+
+// Set defines the named variable, replacing any previous value.
+func (env *Environment[T]) Set(name string, v T) {
+	env.variables[name] = v
+}
+
+// Names returns the names of the variables defined in env itself, rather than its parents, in sorted order.
+func (env *Environment[T]) Names() []string {
+	return slices.Sorted(maps.Keys(env.variables))
+}
+
+// resolveIdentifiers replaces every Identifier element with a Number element holding the value of
+// the constant or variable of that name.
+func (p Parser[T]) resolveIdentifiers(elementList lexer.ElementList) (lexer.ElementList, error) {
+	for i, element := range elementList {
+		if element.Token != lexer.Identifier {
+			continue
+		}
+
+		v, err := p.value(element.TokenValue)
+		if err != nil {
+			return nil, err
+		}
+
+		elementList[i] = lexer.Element{Token: lexer.Number, TokenValue: formatNumber(v), Span: element.Span}
+	}
+
+	return elementList, nil
+}
+
+// value returns the value of the named constant or, failing that, of the variable in the Parser's Environment.
+func (p Parser[T]) value(name string) (T, error) {
+	if v, ok := p.constant(name); ok {
+		return v, nil
+	}
+
+	if env, _ := p.environment.(*Environment[T]); env != nil {
+		if v, ok := env.Get(name); ok {
+			return v, nil
+		}
+	}
+
+	return 0, fmt.Errorf("%w: %w: %s", ErrEvaluation, errUndefinedIdentifier, name)
+}
+
+// constant returns the value of the named constant and whether it is defined.
+func (p Parser[T]) constant(name string) (T, bool) {
+	constants, _ := p.constants.(map[string]T)
+	v, ok := constants[name]
+
+	return v, ok
+}
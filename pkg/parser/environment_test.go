@@ -0,0 +1,68 @@
+package parser
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/lexer"
+)
+
+func TestEnvironment(t *testing.T) {
+	env := NewEnvironment[int]()
+	env.Set("b", 2)
+	env.Set("a", 1)
+	env.Set("b", 3)
+
+	if v, ok := env.Get("b"); !ok || v != 3 {
+		t.Fatalf("Get(b) = (%d,%v), want (3,true)", v, ok)
+	}
+
+	if _, ok := env.Get("c"); ok {
+		t.Fatalf("Get(c) found an undefined variable")
+	}
+
+	if got := env.Names(); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Fatalf("Names() = %v, want [a b]", got)
+	}
+}
+
+func TestParser_EvalWithEnvironment(t *testing.T) {
+	env := NewEnvironment[int]()
+	env.Set("x", 6)
+
+	// x*(x+1)
+	elements := lexer.ElementList{
+		{Token: lexer.Identifier, TokenValue: "x"},
+		{Token: lexer.Multiply, TokenValue: "*"},
+		{Token: lexer.LParen, TokenValue: "("},
+		{Token: lexer.Identifier, TokenValue: "x"},
+		{Token: lexer.Plus, TokenValue: "+"},
+		{Token: lexer.Number, TokenValue: "1"},
+		{Token: lexer.RParen, TokenValue: ")"},
+	}
+
+	base := newTestParser()
+	p := mustNewParser(base.Operations, base.OperationGroups, WithEnvironment(env))
+
+	got, err := p.Eval(elements)
+	if err != nil || *got != 42 {
+		t.Fatalf("Eval() = (%v,%v), want 42", got, err)
+	}
+
+	// The parser must not substitute into the caller's slice.
+	if elements[0].Token != lexer.Identifier {
+		t.Fatalf("Eval() modified its input: %v", elements)
+	}
+
+	_, err = base.Eval(elements)
+	if !errors.Is(err, errUndefinedIdentifier) {
+		t.Fatalf("Eval() without environment err=%v, want errUndefinedIdentifier", err)
+	}
+
+	// An environment of a different Number type is rejected rather than ignored.
+	_, err = NewParser(base.Operations, base.OperationGroups, WithEnvironment(NewEnvironment[float64]()))
+	if !errors.Is(err, errInvalidConfiguration) {
+		t.Fatalf("NewParser() with mismatched environment err=%v, want errInvalidConfiguration", err)
+	}
+}
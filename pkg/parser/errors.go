@@ -1,10 +1,42 @@
 package parser
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/lexer"
+)
+
+// ErrEvaluation is wrapped by errors that arise while evaluating a well-formed expression, such as an
+// Operation failing (e.g. division by zero) or an undefined identifier.  Any other error from Eval means
+// that the expression itself is malformed, and wraps ErrSyntax unless the Parser's configuration is invalid.
+var ErrEvaluation = errors.New("evaluation error")
+
+// ErrDomain should be wrapped by errors from a BuiltinFunction or Operation whose arguments are outside
+// its domain, such as the square root of a negative number.
+var ErrDomain = errors.New("argument out of domain")
+
+// ErrLimitExceeded is wrapped by errors from evaluations that exceed the Limits set by WithLimits.
+var ErrLimitExceeded = errors.New("limit exceeded")
+
+// ErrSyntax is wrapped by errors for expressions that are malformed, such as 1+ or (1, rather than ones
+// that can't be evaluated.  It is lexer.ErrSyntax, so one check covers errors from both packages.
+var ErrSyntax = lexer.ErrSyntax
 
 var (
-	errInvalidOperation = errors.New("invalid operation")
-	errInvalidExpression = errors.New("invalid expression")
-	errIndexOutOfRange = errors.New("index out of range")
-	errInvalidTokenId   = errors.New("invalid TokenId")
+	errInvalidOperation     = fmt.Errorf("%w: invalid operation", ErrSyntax)
+	errInvalidExpression    = fmt.Errorf("%w: invalid expression", ErrSyntax)
+	errIndexOutOfRange      = fmt.Errorf("%w: index out of range", ErrSyntax)
+	errInvalidTokenId       = fmt.Errorf("%w: invalid TokenId", ErrSyntax)
+	errUndefinedIdentifier  = errors.New("undefined identifier")
+	errNonAssociative       = fmt.Errorf("%w: non-associative operators cannot be chained", ErrSyntax)
+	errInvalidNumber        = fmt.Errorf("%w: invalid number", ErrSyntax)
+	errIntegerExponent      = fmt.Errorf("%w: exponent notation needs float mode", errInvalidNumber)
+	errInvalidConfiguration = errors.New("invalid parser configuration")
+	errInvalidAssignment    = fmt.Errorf("%w: cannot assign", ErrSyntax)
+	errInvalidDefinition    = fmt.Errorf("%w: invalid function definition", ErrSyntax)
+	errArity                = errors.New("wrong number of arguments")
+	errCallDepth            = errors.New("function calls nested too deeply")
+	errNoSpelling           = fmt.Errorf("%w: operator has no spelling", ErrSyntax)
+	errUnknownWord          = fmt.Errorf("%w: unknown word", ErrSyntax)
 )
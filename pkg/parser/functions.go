@@ -0,0 +1,211 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/lexer"
+)
+
+// DefaultMaxCallDepth is the deepest nesting of Function calls allowed unless WithMaxCallDepth is used.
+const DefaultMaxCallDepth = 100
+
+// evalCalls replaces every call to a Function in the Parser's Environment or a BuiltinFunction, such as
+// f(3, 4), with a Number element holding its result.  An Identifier followed by an LParen that isn't a
+// Function is left alone, so that it can be an implicit multiplication.
+func (p Parser[T]) evalCalls(elementList lexer.ElementList) (lexer.ElementList, error) {
+	for i := 0; i < len(elementList)-1; i++ {
+		if elementList[i].Token != lexer.Identifier || elementList[i+1].Token != lexer.LParen {
+			continue
+		}
+
+		name := elementList[i].TokenValue
+
+		fn, builtin, ok := p.function(name)
+		if !ok {
+			continue
+		}
+
+		rParenIdx, err := elementList.FindRParen(i + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		args, err := p.evalArguments(elementList[i+2 : rParenIdx])
+		if err != nil {
+			return nil, err
+		}
+
+		val, err := p.invoke(name, fn, builtin, args)
+		if err != nil {
+			return nil, err
+		}
+
+		span := spanOf(elementList[i : rParenIdx+1])
+		p.record(Step[T]{Description: name, Expression: callExpression(name, args), Operands: args, Result: val, Span: span})
+
+		// Replace the name, parentheses and arguments with the result.  The result is kept in parentheses
+		// so that implicit multiplication still sees 3f(2) as 3*(f(2)).
+		remainder := make(lexer.ElementList, len(elementList[rParenIdx+1:]))
+		copy(remainder, elementList[rParenIdx+1:])
+		elementList = append(elementList[:i],
+			lexer.Element{Token: lexer.LParen, TokenValue: "("},
+			lexer.Element{Token: lexer.Number, TokenValue: formatNumber(val), Span: span},
+			lexer.Element{Token: lexer.RParen, TokenValue: ")"},
+		)
+		elementList = append(elementList, remainder...)
+		i += 2
+	}
+
+	return elementList, nil
+}
+
+// function returns the Function in the Parser's Environment or, failing that, the BuiltinFunction called
+// name, and whether there is one.
+func (p Parser[T]) function(name string) (Function, *BuiltinFunction[T], bool) {
+	if env, _ := p.environment.(*Environment[T]); env != nil {
+		if fn, ok := env.Function(name); ok {
+			return fn, nil, true
+		}
+	}
+
+	builtin := p.builtin(name)
+
+	return Function{}, builtin, builtin != nil
+}
+
+// invoke calls builtin with args or, if it is nil, the Function fn, counting the call against the Parser's Limits.
+func (p Parser[T]) invoke(name string, fn Function, builtin *BuiltinFunction[T], args []T) (T, error) {
+	if err := p.spend(); err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrEvaluation, err)
+	}
+
+	var (
+		val T
+		err error
+	)
+
+	if builtin != nil {
+		val, err = callBuiltin(builtin, args)
+	} else {
+		env, _ := p.environment.(*Environment[T])
+		val, err = p.call(env, name, fn, args)
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	if err := p.checkMagnitude(val); err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrEvaluation, err)
+	}
+
+	return val, nil
+}
+
+// evalArguments evaluates each of the comma separated arguments of a call.
+func (p Parser[T]) evalArguments(elementList lexer.ElementList) ([]T, error) {
+	parts := splitArguments(elementList)
+	args := make([]T, 0, len(parts))
+
+	for _, part := range parts {
+		val, err := p.eval(part)
+		if err != nil {
+			return nil, err
+		}
+
+		args = append(args, *val)
+	}
+
+	return args, nil
+}
+
+// splitArguments splits the elements between the parentheses of a call into its arguments.  Only commas
+// outside any parentheses separate arguments, e.g. f(g(1, 2), 3) has two.
+func splitArguments(elementList lexer.ElementList) []lexer.ElementList {
+	if len(elementList) == 0 {
+		return nil
+	}
+
+	var (
+		parts []lexer.ElementList
+		depth int
+		start int
+	)
+
+	for i, element := range elementList {
+		switch element.Token {
+		case lexer.LParen:
+			depth++
+		case lexer.RParen:
+			depth--
+		case lexer.Comma:
+			if depth == 0 {
+				parts = append(parts, elementList[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	return append(parts, elementList[start:])
+}
+
+// call evaluates the Body of fn in a new Environment holding its parameters.
+func (p Parser[T]) call(env *Environment[T], name string, fn Function, args []T) (T, error) {
+	if len(args) != len(fn.Params) {
+		return 0, fmt.Errorf("%w: %w: %s takes %d, got %d", ErrEvaluation, errArity, name, len(fn.Params), len(args))
+	}
+
+	maxDepth := p.maxCallDepth
+	if maxDepth == 0 {
+		maxDepth = DefaultMaxCallDepth
+	}
+
+	if p.callDepth >= maxDepth {
+		return 0, fmt.Errorf("%w: %w: more than %d calls in %s", ErrEvaluation, errCallDepth, maxDepth, name)
+	}
+
+	// The Body may come from an Environment rather than the input, so its parentheses haven't been checked.
+	if err := p.checkNesting(fn.Body); err != nil {
+		return 0, fmt.Errorf("%s: %w", name, err)
+	}
+
+	callee := p
+	callee.environment = env.call(fn.Params, args)
+	callee.callDepth++
+
+	val, err := callee.eval(fn.Body)
+	if err != nil {
+		return 0, err
+	}
+
+	return *val, nil
+}
+
+// builtin returns the BuiltinFunction with the given name, or nil.
+func (p Parser[T]) builtin(name string) *BuiltinFunction[T] {
+	fns, _ := p.builtins.([]BuiltinFunction[T])
+	for i := range fns {
+		if fns[i].Name == name {
+			return &fns[i]
+		}
+	}
+
+	return nil
+}
+
+// callBuiltin checks the number of args and calls fn.
+func callBuiltin[T Number](fn *BuiltinFunction[T], args []T) (T, error) {
+	switch {
+	case len(args) < fn.MinArgs:
+		return 0, fmt.Errorf("%w: %w: %s takes at least %d, got %d", ErrEvaluation, errArity, fn.Name, fn.MinArgs, len(args))
+	case fn.MaxArgs >= 0 && len(args) > fn.MaxArgs:
+		return 0, fmt.Errorf("%w: %w: %s takes at most %d, got %d", ErrEvaluation, errArity, fn.Name, fn.MaxArgs, len(args))
+	}
+
+	val, err := fn.Fn(args...)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s: %w", ErrEvaluation, fn.Name, err)
+	}
+
+	return val, nil
+}
@@ -0,0 +1,189 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/lexer"
+)
+
+// script builds an ElementList from space separated words, treating names as Identifiers.
+func script(s string) lexer.ElementList {
+	tokens := map[string]lexer.TokenId{
+		"+": lexer.Plus, "-": lexer.Minus, "*": lexer.Multiply, "^": lexer.Exponent,
+		"(": lexer.LParen, ")": lexer.RParen, "=": lexer.Assign, ";": lexer.Semicolon, ",": lexer.Comma,
+	}
+
+	var list lexer.ElementList
+
+	for _, word := range strings.Fields(s) {
+		tok, ok := tokens[word]
+		switch {
+		case ok:
+		case word[0] >= '0' && word[0] <= '9':
+			tok = lexer.Number
+		default:
+			tok = lexer.Identifier
+		}
+
+		list = append(list, lexer.Element{Token: tok, TokenValue: word})
+	}
+
+	return list
+}
+
+func TestParser_Functions(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  string
+		opts    []Option
+		want    *int
+		wantErr error
+	}{
+		{name: "call", script: "f ( x , y ) = x ^ 2 + y ; f ( 3 , 4 )", want: ptrInt(13)},
+		{name: "no parameters", script: "g ( ) = 7 ; g ( ) * 2", want: ptrInt(14)},
+		{name: "nested calls", script: "sq ( x ) = x * x ; sq ( sq ( 2 ) + 1 )", want: ptrInt(25)},
+		{name: "commas inside arguments", script: "f ( a , b ) = a - b ; g ( a , b ) = b ; f ( g ( 1 , 10 ) , 3 )", want: ptrInt(7)},
+		{name: "function calling function", script: "sq ( x ) = x * x ; f ( x ) = sq ( x ) + 1 ; f ( 3 )", want: ptrInt(10)},
+		{name: "parameters shadow globals", script: "x = 100 ; f ( x ) = x + 1 ; f ( 1 ) + x", want: ptrInt(102)},
+		{name: "globals are visible", script: "rate = 3 ; f ( x ) = x * rate ; f ( 2 )", want: ptrInt(6)},
+		{name: "later assignments are visible", script: "f ( x ) = x * rate ; rate = 3 ; f ( 2 )", want: ptrInt(6)},
+		{name: "with implicit multiplication", script: "f ( x ) = 2 x ; 3 f ( 2 )", opts: []Option{WithImplicitMultiplication(lexer.Multiply)}, want: ptrInt(12)},
+		{
+			// g can't see f's parameter x because scoping is lexical rather than dynamic.
+			name:    "caller's parameters are not visible",
+			script:  "g ( ) = x ; f ( x ) = g ( ) ; f ( 1 )",
+			wantErr: errUndefinedIdentifier,
+		},
+		{name: "too few arguments", script: "f ( x , y ) = x ; f ( 1 )", wantErr: errArity},
+		{name: "too many arguments", script: "f ( ) = 1 ; f ( 1 )", wantErr: errArity},
+		{name: "recursion limit", script: "f ( x ) = f ( x + 1 ) ; f ( 1 )", wantErr: errCallDepth},
+		{name: "configurable recursion limit", script: "f ( x ) = x ; g ( x ) = f ( x ) ; g ( 1 )", opts: []Option{WithMaxCallDepth(1)}, wantErr: errCallDepth},
+		{name: "repeated parameter", script: "f ( x , x ) = x", wantErr: errInvalidDefinition},
+		{name: "parameter that isn't a name", script: "f ( 1 ) = 1", wantErr: errInvalidDefinition},
+		{name: "empty body", script: "f ( x ) =", wantErr: errInvalidAssignment},
+		{name: "undefined function", script: "f ( 1 )", wantErr: errUndefinedIdentifier},
+	}
+
+	base := newTestParser()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := mustNewParser(base.Operations, base.OperationGroups, tt.opts...)
+
+			got, err := p.EvalStatements(script(tt.script))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("EvalStatements() err=%v wantErr=%v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if got == nil || *got != *tt.want {
+				t.Fatalf("EvalStatements() got=%v want=%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParser_EvalStatementsOnlyDefinitions(t *testing.T) {
+	env := NewEnvironment[int]()
+	base := newTestParser()
+	p := mustNewParser(base.Operations, base.OperationGroups, WithEnvironment(env))
+
+	got, err := p.EvalStatements(script("f ( x ) = x + 1"))
+	if err != nil || got != nil {
+		t.Fatalf("EvalStatements() = (%v, %v), want (nil, nil)", got, err)
+	}
+
+	if fn, ok := env.Function("f"); !ok || len(fn.Params) != 1 {
+		t.Fatalf("Function(\"f\") = (%v, %v)", fn, ok)
+	}
+}
+
+func TestParser_Builtins(t *testing.T) {
+	builtins := []BuiltinFunction[int]{
+		{Name: "max", MinArgs: 1, MaxArgs: -1, Fn: func(args ...int) (int, error) { return slices.Max(args), nil }},
+		{Name: "half", MinArgs: 1, MaxArgs: 1, Fn: func(args ...int) (int, error) {
+			if args[0]%2 != 0 {
+				return 0, fmt.Errorf("%w: %d is odd", ErrDomain, args[0])
+			}
+
+			return args[0] / 2, nil
+		}},
+	}
+
+	tests := []struct {
+		name    string
+		script  string
+		want    *int
+		wantErr error
+	}{
+		{name: "call", script: "half ( 10 ) + 1", want: ptrInt(6)},
+		{name: "variadic", script: "max ( 3 , 9 , 4 )", want: ptrInt(9)},
+		{name: "nested", script: "half ( max ( 2 , half ( 12 ) ) )", want: ptrInt(3)},
+		{name: "user function calling builtin", script: "f ( x ) = half ( x ) ; f ( 8 )", want: ptrInt(4)},
+		{name: "user function takes priority", script: "half ( x ) = x ; half ( 3 )", want: ptrInt(3)},
+		{name: "domain error", script: "half ( 3 )", wantErr: ErrDomain},
+		{name: "too few arguments", script: "max ( )", wantErr: errArity},
+		{name: "too many arguments", script: "half ( 2 , 4 )", wantErr: errArity},
+	}
+
+	base := newTestParser()
+	p := mustNewParser(base.Operations, base.OperationGroups, WithBuiltins(builtins))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := p.EvalStatements(script(tt.script))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("EvalStatements() err=%v wantErr=%v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if got == nil || *got != *tt.want {
+				t.Fatalf("EvalStatements() got=%v want=%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParser_Constants(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  string
+		want    *int
+		wantErr error
+	}{
+		{name: "constant", script: "answer + 1", want: ptrInt(43)},
+		{name: "in a function", script: "f ( x ) = x * answer ; f ( 2 )", want: ptrInt(84)},
+		{name: "assignment", script: "answer = 1", wantErr: errInvalidAssignment},
+		{name: "parameter", script: "f ( answer ) = answer", wantErr: errInvalidDefinition},
+		{name: "function", script: "answer ( x ) = x ; answer ( 5 )", wantErr: errInvalidDefinition},
+	}
+
+	base := newTestParser()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := NewEnvironment[int]()
+			env.Set("answer", 0)
+
+			p := mustNewParser(base.Operations, base.OperationGroups,
+				WithConstants(map[string]int{"answer": 42}), WithEnvironment(env))
+
+			got, err := p.EvalStatements(script(tt.script))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("EvalStatements() err=%v wantErr=%v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if got == nil || *got != *tt.want {
+				t.Fatalf("EvalStatements() got=%v want=%v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,234 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/lexer"
+)
+
+// SchemaVersion is the version of the JSON representation written by MarshalNode.  It changes whenever
+// the representation changes in a way that older versions of UnmarshalNode couldn't read.
+const SchemaVersion = 1
+
+// jsonExpression is the JSON representation of an expression: its Nodes in post-order, so that the operands
+// of each node come before it and the last node is the root.
+type jsonExpression struct {
+	Version int         `json:"version"`
+	Nodes   []*jsonNode `json:"nodes"`
+}
+
+// jsonNode is the JSON representation of a Node.  Value holds the literal of a number and the name of an
+// identifier or call.  Operator holds the spelling of an operator, and Operands the indexes of its operands.
+type jsonNode struct {
+	Type     string    `json:"type"`
+	Value    string    `json:"value,omitempty"`
+	Operator string    `json:"operator,omitempty"`
+	Operands []int     `json:"operands,omitempty"`
+	Span     *jsonSpan `json:"span,omitempty"`
+}
+
+type jsonSpan struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// MarshalNode returns the JSON representation of n, so that an expression parsed once can be stored and
+// evaluated elsewhere without parsing its source text again.  For example 2 * x is
+//
+//	{
+//	  "version": 1,
+//	  "nodes": [
+//	    {"type": "number", "value": "2", "span": {"start": 0, "end": 1}},
+//	    {"type": "identifier", "value": "x", "span": {"start": 4, "end": 5}},
+//	    {"type": "infix", "operator": "*", "operands": [0, 1], "span": {"start": 0, "end": 5}}
+//	  ]
+//	}
+//
+// The nodes are listed in post-order, with each operator's operands given by their indexes, so the nesting
+// of the JSON doesn't grow with the expression.  The last node is the root.  type is a NodeType's String.
+// Each operator is written with its first spelling in tokens, and an infix operator with no spelling, such as
+// lexer.ImplicitMultiply, with none.  Spans are omitted if the lexer didn't record them, and Comments always
+// are.  version is SchemaVersion.
+func MarshalNode(n Node, tokens []lexer.Token) ([]byte, error) {
+	e := jsonExpression{Version: SchemaVersion}
+	if _, err := e.add(n, NewPrinter(tokens, nil)); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(e)
+}
+
+// add appends n and its operands to e's Nodes and returns n's index.
+func (e *jsonExpression) add(n Node, pr Printer) (int, error) {
+	if err := checkOperands(n); err != nil {
+		return 0, err
+	}
+
+	j := &jsonNode{Type: n.Type.String()}
+
+	switch n.Type {
+	case NumberNode, IdentifierNode, CallNode:
+		j.Value = n.Value
+	default:
+		if j.Operator = pr.Spelling(n.Token); j.Operator == "" && n.Type != InfixNode {
+			return 0, fmt.Errorf("%w: TokenId %d", errNoSpelling, n.Token)
+		}
+	}
+
+	if n.Span != (lexer.Span{}) {
+		j.Span = &jsonSpan{Start: n.Span.Start, End: n.Span.End}
+	}
+
+	for _, operand := range n.Operands {
+		i, err := e.add(operand, pr)
+		if err != nil {
+			return 0, err
+		}
+
+		j.Operands = append(j.Operands, i)
+	}
+
+	e.Nodes = append(e.Nodes, j)
+
+	return len(e.Nodes) - 1, nil
+}
+
+// UnmarshalNode reads a Node from JSON written by MarshalNode, and checks that it can be evaluated by a
+// Parser with opGroups: the version must be SchemaVersion, the nodes must form a single tree, every node
+// must have the number of operands its type needs, numbers and names must be read as such by a lexer with
+// tokens, and each operator must be spelled by tokens and be in an OperationGroup of the right Fixity.  An
+// infix operator with no spelling is lexer.ImplicitMultiply.  Numbers are normalised as the lexer
+// normalises them, and negative ones are read as ParseRPN reads them.
+func UnmarshalNode(data []byte, tokens []lexer.Token, opGroups []OperationGroup) (Node, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	var e jsonExpression
+	if err := dec.Decode(&e); err != nil {
+		return Node{}, fmt.Errorf("%w: %w", errInvalidExpression, err)
+	}
+
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return Node{}, fmt.Errorf("%w: data after the expression", errInvalidExpression)
+	}
+
+	if e.Version != SchemaVersion {
+		return Node{}, fmt.Errorf("%w: schema version %d, want %d", errInvalidExpression, e.Version, SchemaVersion)
+	}
+
+	if len(e.Nodes) == 0 {
+		return Node{}, fmt.Errorf("%w: no nodes", errInvalidExpression)
+	}
+
+	nodes := make([]Node, len(e.Nodes))
+	used := make([]bool, len(e.Nodes))
+
+	for i, j := range e.Nodes {
+		if j == nil {
+			return Node{}, fmt.Errorf("%w: node %d is null", errInvalidExpression, i)
+		}
+
+		n, err := fromJSON(j, tokens, opGroups)
+		if err != nil {
+			return Node{}, fmt.Errorf("node %d: %w", i, err)
+		}
+
+		// Each node may only be the operand of one later node, so that the nodes form a tree.
+		for _, k := range j.Operands {
+			if k < 0 || k >= i || used[k] {
+				return Node{}, fmt.Errorf("%w: node %d: operand %d isn't an unused earlier node", errInvalidExpression, i, k)
+			}
+
+			used[k] = true
+			n.Operands = append(n.Operands, nodes[k])
+		}
+
+		if err := checkOperands(n); err != nil {
+			return Node{}, fmt.Errorf("node %d: %w", i, err)
+		}
+
+		nodes[i] = n
+	}
+
+	if unused := slices.Index(used[:len(used)-1], false); unused >= 0 {
+		return Node{}, fmt.Errorf("%w: node %d isn't an operand", errInvalidExpression, unused)
+	}
+
+	return nodes[len(nodes)-1], nil
+}
+
+// fromJSON returns the Node for j, without its Operands.
+func fromJSON(j *jsonNode, tokens []lexer.Token, opGroups []OperationGroup) (Node, error) {
+	n := Node{Type: -1}
+
+	for t, name := range nodeTypeNames {
+		if name == j.Type {
+			n.Type = t
+		}
+	}
+
+	if n.Type < 0 {
+		return Node{}, fmt.Errorf("%w: unknown node type %q", errInvalidExpression, j.Type)
+	}
+
+	if j.Span != nil {
+		if j.Span.Start < 0 || j.Span.End < j.Span.Start {
+			return Node{}, fmt.Errorf("%w: %s node with span [%d:%d]", errInvalidExpression, j.Type, j.Span.Start, j.Span.End)
+		}
+
+		n.Span = lexer.Span{Start: j.Span.Start, End: j.Span.End}
+	}
+
+	switch n.Type {
+	case NumberNode:
+		number, ok := readNumber(j.Value, n.Span, tokens, opGroups)
+		if !ok {
+			return Node{}, fmt.Errorf("%w: %q", errInvalidNumber, j.Value)
+		}
+
+		n = number
+	case IdentifierNode, CallNode:
+		if !isName(j.Value, tokens) {
+			return Node{}, fmt.Errorf("%w: %s node with name %q", errInvalidExpression, j.Type, j.Value)
+		}
+
+		n.Value = j.Value
+	default:
+		tok, err := operatorToken(j.Operator, n.Type, tokens)
+		if err != nil {
+			return Node{}, err
+		}
+
+		if groupIndex(opGroups, tok, n.Type.fixity()) < 0 {
+			return Node{}, fmt.Errorf("%w: %q isn't in a %s OperationGroup", errInvalidOperation, j.Operator, n.Type)
+		}
+
+		n.Token, n.Value = tok, j.Operator
+	}
+
+	return n, nil
+}
+
+// operatorToken returns the TokenId that tokens spell as spelling, or lexer.ImplicitMultiply if spelling is
+// empty and the operator is infix.
+func operatorToken(spelling string, t NodeType, tokens []lexer.Token) (lexer.TokenId, error) {
+	if spelling == "" {
+		if t != InfixNode {
+			return 0, fmt.Errorf("%w: %s node", errNoSpelling, t)
+		}
+
+		return lexer.ImplicitMultiply, nil
+	}
+
+	e, err := lexer.NewLexer(spelling, tokens, lexer.WithIdentifiers()).GetElementList()
+	if err != nil || len(e) != 1 || e[0].Token == lexer.Number || e[0].Token == lexer.Identifier {
+		return 0, fmt.Errorf("%w: %q isn't an operator", errInvalidOperation, spelling)
+	}
+
+	return e[0].Token, nil
+}
@@ -0,0 +1,158 @@
+package parser
+
+import (
+	"errors"
+	"math/rand/v2"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/lexer"
+)
+
+func TestMarshalNode(t *testing.T) {
+	_, loose, _ := printerParsers()
+
+	e, err := lexer.NewLexer("2 × -x", printerTokens, lexer.WithIdentifiers(), lexer.WithSpans()).GetElementList()
+	if err != nil {
+		t.Fatalf("GetElementList() err=%v", err)
+	}
+
+	n, err := loose.Parse(e)
+	if err != nil {
+		t.Fatalf("Parse() err=%v", err)
+	}
+
+	data, err := MarshalNode(n, printerTokens)
+	if err != nil {
+		t.Fatalf("MarshalNode() err=%v", err)
+	}
+
+	// Operators are written with their first spelling, so × is written *.
+	want := `{"version":1,"nodes":[` +
+		`{"type":"number","value":"2","span":{"start":0,"end":1}},` +
+		`{"type":"identifier","value":"x","span":{"start":6,"end":7}},` +
+		`{"type":"prefix","operator":"-","operands":[1],"span":{"start":5,"end":7}},` +
+		`{"type":"infix","operator":"*","operands":[0,2],"span":{"start":0,"end":7}}]}`
+
+	if string(data) != want {
+		t.Fatalf("MarshalNode() got=%s\nwant=%s", data, want)
+	}
+
+	got, err := UnmarshalNode(data, printerTokens, loose.OperationGroups)
+	if n.Value = "*"; err != nil || !reflect.DeepEqual(got, n) {
+		t.Fatalf("UnmarshalNode() = (%v, %v), want %v", got, err, n)
+	}
+}
+
+// TestMarshalNode_RoundTrip checks that random trees, including implicit multiplication and calls, are
+// read back unchanged.
+func TestMarshalNode_RoundTrip(t *testing.T) {
+	tight, loose, implicit := printerParsers()
+	r := rand.New(rand.NewPCG(5, 6))
+
+	for _, p := range []Parser[int]{tight, loose, implicit} {
+		for range 500 {
+			want := randomNode(r, p, 4)
+			if p.implicitMultiply != lexer.NullToken {
+				want = Node{Type: InfixNode, Token: lexer.ImplicitMultiply, Operands: []Node{
+					{Type: NumberNode, Value: "2", Span: lexer.Span{Start: 0, End: 1}},
+					{Type: CallNode, Value: "max", Operands: []Node{want, {Type: NumberNode, Value: "3"}}},
+				}}
+			}
+
+			data, err := MarshalNode(want, printerTokens)
+			if err != nil {
+				t.Fatalf("MarshalNode(%v) err=%v", want, err)
+			}
+
+			got, err := UnmarshalNode(data, printerTokens, p.OperationGroups)
+			if err != nil || !reflect.DeepEqual(got, want) {
+				t.Fatalf("UnmarshalNode(%s) = (%v, %v), want %v", data, got, err, want)
+			}
+		}
+	}
+}
+
+// TestMarshalNode_Long checks that the JSON of a long left associative chain isn't nested deeply, since
+// encoding/json refuses to read deeply nested data.
+func TestMarshalNode_Long(t *testing.T) {
+	_, loose, _ := printerParsers()
+
+	n, err := loose.Parse(script(strings.Repeat("1 + ", 20000) + "1"))
+	if err != nil {
+		t.Fatalf("Parse() err=%v", err)
+	}
+
+	data, err := MarshalNode(n, printerTokens)
+	if err != nil {
+		t.Fatalf("MarshalNode() err=%v", err)
+	}
+
+	got, err := UnmarshalNode(data, printerTokens, loose.OperationGroups)
+	if err != nil || !reflect.DeepEqual(got, n) {
+		t.Fatalf("UnmarshalNode() err=%v", err)
+	}
+}
+
+func TestUnmarshalNode(t *testing.T) {
+	_, loose, _ := printerParsers()
+
+	one := `{"type":"number","value":"1"}`
+
+	tests := []struct {
+		name    string
+		data    string
+		want    Node
+		wantErr error
+	}{
+		{name: "normalised number", data: `{"version":1,"nodes":[{"type":"number","value":"0x1_0"}]}`,
+			want: Node{Type: NumberNode, Value: "16"}},
+		{name: "negative number", data: `{"version":1,"nodes":[{"type":"number","value":"-5","span":{"start":0,"end":2}}]}`,
+			want: Node{Type: PrefixNode, Token: lexer.Minus, Value: "-", Span: lexer.Span{Start: 0, End: 2},
+				Operands: []Node{{Type: NumberNode, Value: "5", Span: lexer.Span{Start: 1, End: 2}}}}},
+		{name: "second spelling", data: `{"version":1,"nodes":[{"type":"number","value":"2"},{"type":"identifier","value":"x"},{"type":"infix","operator":"×","operands":[0,1]}]}`,
+			want: Node{Type: InfixNode, Token: lexer.Multiply, Value: "×", Operands: []Node{{Type: NumberNode, Value: "2"}, {Type: IdentifierNode, Value: "x"}}}},
+		{name: "error - syntax", data: `{"version":1,`, wantErr: errInvalidExpression},
+		{name: "error - unknown field", data: `{"version":1,"nodes":[{"type":"number","value":"1","units":"m"}]}`, wantErr: errInvalidExpression},
+		{name: "error - trailing data", data: `{"version":1,"nodes":[` + one + `]} {}`, wantErr: errInvalidExpression},
+		{name: "error - version", data: `{"version":2,"nodes":[` + one + `]}`, wantErr: errInvalidExpression},
+		{name: "error - no version", data: `{"nodes":[` + one + `]}`, wantErr: errInvalidExpression},
+		{name: "error - no nodes", data: `{"version":1}`, wantErr: errInvalidExpression},
+		{name: "error - null node", data: `{"version":1,"nodes":[null]}`, wantErr: errInvalidExpression},
+		{name: "error - unknown type", data: `{"version":1,"nodes":[{"type":"matrix"}]}`, wantErr: errInvalidExpression},
+		{name: "error - invalid number", data: `{"version":1,"nodes":[{"type":"number","value":"1+1"}]}`, wantErr: errInvalidNumber},
+		{name: "error - invalid name", data: `{"version":1,"nodes":[{"type":"identifier","value":"2x"}]}`, wantErr: errInvalidExpression},
+		{name: "error - missing operand", data: `{"version":1,"nodes":[` + one + `,{"type":"infix","operator":"+","operands":[0]}]}`,
+			wantErr: errInvalidExpression},
+		{name: "error - operands of a number", data: `{"version":1,"nodes":[` + one + `,{"type":"number","value":"2","operands":[0]}]}`,
+			wantErr: errInvalidExpression},
+		{name: "error - later operand", data: `{"version":1,"nodes":[{"type":"call","value":"f","operands":[1]},` + one + `]}`,
+			wantErr: errInvalidExpression},
+		{name: "error - shared operand", data: `{"version":1,"nodes":[` + one + `,{"type":"infix","operator":"+","operands":[0,0]}]}`,
+			wantErr: errInvalidExpression},
+		{name: "error - unused node", data: `{"version":1,"nodes":[` + one + `,` + one + `]}`, wantErr: errInvalidExpression},
+		{name: "error - wrong fixity", data: `{"version":1,"nodes":[` + one + `,{"type":"prefix","operator":"+","operands":[0]}]}`,
+			wantErr: errInvalidOperation},
+		{name: "error - unknown operator", data: `{"version":1,"nodes":[` + one + `,` + one + `,{"type":"infix","operator":"**","operands":[0,1]}]}`,
+			wantErr: errInvalidOperation},
+		{name: "error - no spelling", data: `{"version":1,"nodes":[` + one + `,{"type":"postfix","operands":[0]}]}`,
+			wantErr: errNoSpelling},
+		{name: "error - implicit multiplication", data: `{"version":1,"nodes":[` + one + `,` + one + `,{"type":"infix","operands":[0,1]}]}`,
+			wantErr: errInvalidOperation},
+		{name: "error - span", data: `{"version":1,"nodes":[{"type":"number","value":"1","span":{"start":3,"end":2}}]}`, wantErr: errInvalidExpression},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := UnmarshalNode([]byte(tt.data), printerTokens, loose.OperationGroups)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UnmarshalNode() err=%v wantErr=%v", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("UnmarshalNode() got=%v want=%v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/lexer"
+)
+
+// DefaultMaxDepth is the deepest nesting of parentheses allowed unless Limits.MaxDepth is set.  Each level
+// of parentheses is evaluated by a recursive call, so without a bound a long enough run of ( would
+// exhaust the stack.
+const DefaultMaxDepth = 1000
+
+// Limits bounds the work done evaluating an expression, for expressions from untrusted sources.  A zero
+// field means no limit, except for MaxDepth.  Exceeding a limit gives an error wrapping ErrLimitExceeded.
+type Limits struct {
+	// MaxTokens limits the number of elements in the expression.
+	MaxTokens int
+	// MaxDepth limits how deeply parentheses, including those of function calls, may be nested in the
+	// expression and in the Body of each Function it calls.  Zero means DefaultMaxDepth.
+	MaxDepth int
+	// MaxOperations limits the number of Operations applied and functions called.
+	MaxOperations int
+	// MaxMagnitude limits the absolute value of the result of each Operation and function, which stops
+	// 9^9^9^9 from overflowing to +Inf.  It is checked after the result has been converted to T, so
+	// Operations on ints must report overflow themselves, as the built-in ones in package language do.
+	MaxMagnitude float64
+}
+
+// WithLimits sets the Limits checked by Eval, EvalContext, EvalStatements and EvalStatementsContext.
+func WithLimits(l Limits) Option {
+	return func(o *options) {
+		o.limits = l
+	}
+}
+
+// budget tracks the work done by one evaluation, which is shared by the copies of the Parser made for
+// parentheses and Function calls.
+type budget struct {
+	ctx        context.Context
+	operations int
+}
+
+// start checks e against the Parser's Limits and returns a copy of the Parser that checks ctx and counts
+// operations while evaluating it.
+func (p Parser[T]) start(ctx context.Context, e lexer.ElementList) (Parser[T], error) {
+	if p.limits.MaxTokens > 0 && len(e) > p.limits.MaxTokens {
+		return p, fmt.Errorf("%w: %w: %d tokens, more than %d", ErrEvaluation, ErrLimitExceeded, len(e), p.limits.MaxTokens)
+	}
+
+	// The nesting is checked before evaluation starts, since evalParen recurses once for each level.
+	if err := p.checkNesting(e); err != nil {
+		return p, err
+	}
+
+	p.budget = &budget{ctx: ctx}
+
+	return p, nil
+}
+
+// checkNesting returns an error if parentheses are nested more deeply in e than the Parser's Limits allow.
+func (p Parser[T]) checkNesting(e lexer.ElementList) error {
+	maxDepth := p.limits.MaxDepth
+	if maxDepth == 0 {
+		maxDepth = DefaultMaxDepth
+	}
+
+	if depth := nesting(e); depth > maxDepth {
+		return fmt.Errorf("%w: %w: parentheses nested %d deep, more than %d",
+			ErrEvaluation, ErrLimitExceeded, depth, maxDepth)
+	}
+
+	return nil
+}
+
+// spend accounts for an Operation or function call, returning an error if the evaluation has been
+// cancelled or has used up its operations.
+func (p Parser[T]) spend() error {
+	if p.budget == nil {
+		return nil
+	}
+
+	if err := p.budget.ctx.Err(); err != nil {
+		return err
+	}
+
+	p.budget.operations++
+	if p.limits.MaxOperations > 0 && p.budget.operations > p.limits.MaxOperations {
+		return fmt.Errorf("%w: more than %d operations", ErrLimitExceeded, p.limits.MaxOperations)
+	}
+
+	return nil
+}
+
+// checkMagnitude returns an error if v is larger in magnitude than the Parser's Limits allow.
+func (p Parser[T]) checkMagnitude(v T) error {
+	if p.limits.MaxMagnitude > 0 && !(math.Abs(float64(v)) <= p.limits.MaxMagnitude) {
+		return fmt.Errorf("%w: %v is larger than %v", ErrLimitExceeded, v, p.limits.MaxMagnitude)
+	}
+
+	return nil
+}
+
+// nesting returns the deepest nesting of parentheses in e.
+func nesting(e lexer.ElementList) int {
+	var depth, deepest int
+
+	for _, element := range e {
+		switch element.Token {
+		case lexer.LParen:
+			depth++
+			deepest = max(deepest, depth)
+		case lexer.RParen:
+			depth--
+		}
+	}
+
+	return deepest
+}
@@ -0,0 +1,114 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/lexer"
+)
+
+func TestParser_EvalContextLimits(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name    string
+		script  string
+		limits  Limits
+		ctx     context.Context
+		want    *int
+		wantErr error
+	}{
+		{name: "within limits", script: "( 1 + 2 ) * 3", limits: Limits{MaxTokens: 7, MaxDepth: 1, MaxOperations: 2, MaxMagnitude: 9}, want: ptrInt(9)},
+		{name: "too many tokens", script: "1 + 2 + 3", limits: Limits{MaxTokens: 4}, wantErr: ErrLimitExceeded},
+		{name: "nested too deeply", script: "( ( 1 ) )", limits: Limits{MaxDepth: 1}, wantErr: ErrLimitExceeded},
+		{name: "too many operations", script: "1 + 2 + 3", limits: Limits{MaxOperations: 1}, wantErr: ErrLimitExceeded},
+		{name: "function calls count as operations", script: "f ( x ) = x ; f ( 1 ) + f ( 2 )", limits: Limits{MaxOperations: 2}, wantErr: ErrLimitExceeded},
+		{name: "operations are counted across statements", script: "x = 1 + 1 ; x + 1", limits: Limits{MaxOperations: 1}, wantErr: ErrLimitExceeded},
+		{name: "intermediate result too large", script: "9 ^ 9 ^ 9 ^ 9", limits: Limits{MaxMagnitude: 1e15}, wantErr: ErrLimitExceeded},
+		{name: "cancelled", script: "1 + 2", ctx: cancelled, wantErr: context.Canceled},
+	}
+
+	base := newTestParser()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := tt.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+
+			p := mustNewParser(base.Operations, base.OperationGroups, WithLimits(tt.limits))
+
+			got, err := p.EvalStatementsContext(ctx, script(tt.script))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("EvalStatementsContext() err=%v wantErr=%v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if !errors.Is(err, ErrEvaluation) {
+					t.Fatalf("EvalStatementsContext() err=%v doesn't wrap ErrEvaluation", err)
+				}
+
+				return
+			}
+			if got == nil || *got != *tt.want {
+				t.Fatalf("EvalStatementsContext() got=%v want=%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParser_EvalDeepNesting(t *testing.T) {
+	// nested returns 1 inside depth pairs of parentheses.
+	nested := func(depth int) lexer.ElementList {
+		var list lexer.ElementList
+		for range depth {
+			list = append(list, lexer.Element{Token: lexer.LParen, TokenValue: "("})
+		}
+
+		list = append(list, lexer.Element{Token: lexer.Number, TokenValue: "1"})
+
+		for range depth {
+			list = append(list, lexer.Element{Token: lexer.RParen, TokenValue: ")"})
+		}
+
+		return list
+	}
+
+	base := newTestParser()
+
+	if got, err := base.Eval(nested(DefaultMaxDepth)); err != nil || *got != 1 {
+		t.Fatalf("Eval() at DefaultMaxDepth = (%v, %v), want 1", got, err)
+	}
+
+	// Unbalanced input is rejected by the same check, before the parentheses are matched.
+	for _, e := range []lexer.ElementList{nested(100_000), nested(100_000)[:100_000]} {
+		if _, err := base.Eval(e); !errors.Is(err, ErrLimitExceeded) {
+			t.Fatalf("Eval() of %d elements err=%v, want ErrLimitExceeded", len(e), err)
+		}
+	}
+
+	p := mustNewParser(base.Operations, base.OperationGroups, WithLimits(Limits{MaxDepth: 5000}))
+	if got, err := p.Eval(nested(5000)); err != nil || *got != 1 {
+		t.Fatalf("Eval() with MaxDepth 5000 = (%v, %v), want 1", got, err)
+	}
+
+	if _, err := p.Eval(script(strings.Repeat("( ", 5001) + "1")); !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("Eval() deeper than MaxDepth err=%v, want ErrLimitExceeded", err)
+	}
+
+	// The Body of a Function in the Environment is checked when it is called.
+	env := NewEnvironment[int]()
+	env.Define("f", Function{Params: []string{"x"}, Body: nested(3)})
+
+	shallow := mustNewParser(base.Operations, base.OperationGroups, WithEnvironment(env), WithLimits(Limits{MaxDepth: 2}))
+	if _, err := shallow.Eval(script("f ( 1 )")); !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("Eval() of a Function nested too deeply err=%v, want ErrLimitExceeded", err)
+	}
+
+	if _, err := shallow.EvalNode(Node{Type: CallNode, Value: "f", Operands: []Node{{Type: NumberNode, Value: "1"}}}); !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("EvalNode() of a Function nested too deeply err=%v, want ErrLimitExceeded", err)
+	}
+}
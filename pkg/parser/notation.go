@@ -0,0 +1,251 @@
+package parser
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/lexer"
+)
+
+// unaryMarker starts the word for a prefix or postfix operator in RPN or Polish notation when its TokenId
+// also belongs to an Infix OperationGroup, so that negation is written u- and subtraction -.
+const unaryMarker = "u"
+
+// RPN returns n in Reverse Polish notation, with each operator following its operands and the words
+// separated by spaces, e.g. "(1 + 2) * 3" is "1 2 + 3 *".  Operators are spelled as in Format, except that a
+// prefix or postfix operator whose TokenId is also an infix operator is marked with a leading u, as in
+// "3 u-", and implicit multiplication is written as multiplication.  A call is written as its name followed
+// by / and its number of arguments, so max(1, 2) is "1 2 max/2".  Comments are left out.  ParseRPN reads
+// the result.
+func (pr Printer) RPN(n Node) (string, error) {
+	var words []string
+	if err := pr.notation(n, &words, true); err != nil {
+		return "", err
+	}
+
+	return strings.Join(words, " "), nil
+}
+
+// Polish returns n in Polish notation, which is RPN with each operator preceding its operands instead, e.g.
+// "(1 + 2) * 3" is "* + 1 2 3".  ParsePolish reads the result.
+func (pr Printer) Polish(n Node) (string, error) {
+	var words []string
+	if err := pr.notation(n, &words, false); err != nil {
+		return "", err
+	}
+
+	return strings.Join(words, " "), nil
+}
+
+// notation appends the words for n to words, with operators after their operands if postfix is true
+// and before them otherwise.
+func (pr Printer) notation(n Node, words *[]string, postfix bool) error {
+	if err := checkOperands(n); err != nil {
+		return err
+	}
+
+	var word string
+
+	switch n.Type {
+	case NumberNode, IdentifierNode:
+		*words = append(*words, n.Value)
+		return nil
+	case CallNode:
+		word = n.Value + "/" + strconv.Itoa(len(n.Operands))
+	default:
+		if groupIndex(pr.OperationGroups, n.Token, n.Type.fixity()) < 0 {
+			return fmt.Errorf("%w: TokenId %d isn't in a %s OperationGroup", errInvalidOperation, n.Token, n.Type)
+		}
+
+		tok := n.Token
+		if tok == lexer.ImplicitMultiply && pr.spellings[tok] == "" {
+			tok = lexer.Multiply
+		}
+
+		word = pr.spellings[tok]
+		if word == "" {
+			return fmt.Errorf("%w: TokenId %d", errNoSpelling, n.Token)
+		}
+
+		if n.Type != InfixNode && groupIndex(pr.OperationGroups, n.Token, Infix) >= 0 {
+			word = unaryMarker + word
+		}
+	}
+
+	if !postfix {
+		*words = append(*words, word)
+	}
+
+	for _, operand := range n.Operands {
+		if err := pr.notation(operand, words, postfix); err != nil {
+			return err
+		}
+	}
+
+	if postfix {
+		*words = append(*words, word)
+	}
+
+	return nil
+}
+
+// ParseRPN parses text in Reverse Polish notation, as written by Printer.RPN, into a Node.  Words are
+// separated by white space.  Operators are recognised by their spellings in tokens, and opGroups decide
+// whether an operator is infix, prefix or postfix.  Numbers are normalised as the lexer normalises them, and
+// may be negative, as in "3 -5 +": see readNumber.  Printer.Elements turns the Node into elements that a
+// Parser with opGroups can evaluate.
+func ParseRPN(text string, tokens []lexer.Token, opGroups []OperationGroup) (Node, error) {
+	return parseNotation(text, tokens, opGroups, true)
+}
+
+// ParsePolish parses text in Polish notation, as written by Printer.Polish, into a Node.  See ParseRPN.
+func ParsePolish(text string, tokens []lexer.Token, opGroups []OperationGroup) (Node, error) {
+	return parseNotation(text, tokens, opGroups, false)
+}
+
+// word is a word of RPN or Polish notation and its position in the text.
+type word struct {
+	text string
+	span lexer.Span
+}
+
+// parseNotation parses text in RPN if postfix is true, or else Polish notation.  Polish notation is read
+// from right to left, so that in both cases each operator is read after its operands.
+func parseNotation(text string, tokens []lexer.Token, opGroups []OperationGroup, postfix bool) (Node, error) {
+	words := splitFields(text)
+	if !postfix {
+		slices.Reverse(words)
+	}
+
+	var stack []Node
+
+	for _, w := range words {
+		n, arity, err := readWord(w, tokens, opGroups)
+		if err != nil {
+			return Node{}, err
+		}
+
+		if len(stack) < arity {
+			return Node{}, fmt.Errorf("%w: %q at %d needs %d operands, got %d", errInvalidExpression, w.text, w.span.Start, arity, len(stack))
+		}
+
+		if arity > 0 {
+			n.Operands = slices.Clone(stack[len(stack)-arity:])
+			stack = stack[:len(stack)-arity]
+
+			// Read from right to left, the operands of Polish notation are stacked last first.
+			if !postfix {
+				slices.Reverse(n.Operands)
+			}
+
+			for _, operand := range n.Operands {
+				n.Span = n.Span.Join(operand.Span)
+			}
+		}
+
+		stack = append(stack, n)
+	}
+
+	if len(stack) != 1 {
+		return Node{}, fmt.Errorf("%w: %d values left, want 1", errInvalidExpression, len(stack))
+	}
+
+	return stack[0], nil
+}
+
+// splitFields splits text into words at white space.
+func splitFields(text string) []word {
+	var (
+		words []word
+		start = -1
+	)
+
+	for i, c := range text + " " {
+		switch {
+		case !unicode.IsSpace(c) && start < 0:
+			start = i
+		case unicode.IsSpace(c) && start >= 0:
+			words = append(words, word{text: text[start:i], span: lexer.Span{Start: start, End: i}})
+			start = -1
+		}
+	}
+
+	return words
+}
+
+// readWord returns the Node for w, without its Operands, and the number of Operands it takes.
+func readWord(w word, tokens []lexer.Token, opGroups []OperationGroup) (Node, int, error) {
+	if name, count, ok := strings.Cut(w.text, "/"); ok && name != "" {
+		if arity, err := strconv.Atoi(count); err == nil && arity >= 0 && isName(name, tokens) {
+			return Node{Type: CallNode, Value: name, Span: w.span}, arity, nil
+		}
+	}
+
+	if n, ok := readNumber(w.text, w.span, tokens, opGroups); ok {
+		return n, 0, nil
+	}
+
+	e, err := lexer.NewLexer(w.text, tokens, lexer.WithIdentifiers()).GetElementList()
+	if err == nil && len(e) == 1 {
+		switch tok := e[0].Token; {
+		case tok == lexer.Identifier:
+			return Node{Type: IdentifierNode, Value: e[0].TokenValue, Span: w.span}, 0, nil
+		case groupIndex(opGroups, tok, Infix) >= 0:
+			return Node{Type: InfixNode, Token: tok, Value: w.text, Span: w.span}, 2, nil
+		case groupIndex(opGroups, tok, Prefix) >= 0:
+			return Node{Type: PrefixNode, Token: tok, Value: w.text, Span: w.span}, 1, nil
+		case groupIndex(opGroups, tok, Postfix) >= 0:
+			return Node{Type: PostfixNode, Token: tok, Value: w.text, Span: w.span}, 1, nil
+		}
+	}
+
+	if spelling, ok := strings.CutPrefix(w.text, unaryMarker); ok && spelling != "" {
+		e, err := lexer.NewLexer(spelling, tokens).GetElementList()
+		if err == nil && len(e) == 1 {
+			for _, t := range []NodeType{PrefixNode, PostfixNode} {
+				if groupIndex(opGroups, e[0].Token, t.fixity()) >= 0 {
+					return Node{Type: t, Token: e[0].Token, Value: spelling, Span: w.span}, 1, nil
+				}
+			}
+		}
+	}
+
+	return Node{}, 0, fmt.Errorf("%w: %q at %d", errUnknownWord, w.text, w.span.Start)
+}
+
+// readNumber returns the Node for s, a number literal that the lexer with tokens reads, which may be negative,
+// and whether s is one.  The minus of a negative number is the prefix minus of opGroups, if there is one, so
+// that the Node is printed as the language would write it.  Otherwise, -5 is read as a NumberNode, since
+// there would be no other way to write a negative number.
+func readNumber(s string, span lexer.Span, tokens []lexer.Token, opGroups []OperationGroup) (Node, bool) {
+	e, err := lexer.NewLexer(s, tokens).GetElementList()
+	if err != nil || len(e) == 0 || e[len(e)-1].Token != lexer.Number {
+		return Node{}, false
+	}
+
+	switch {
+	case len(e) == 1:
+		return Node{Type: NumberNode, Value: e[0].TokenValue, Span: span}, true
+	case len(e) != 2 || e[0].Token != lexer.Minus:
+		return Node{}, false
+	case groupIndex(opGroups, lexer.Minus, Prefix) >= 0:
+		literal := Node{Type: NumberNode, Value: e[1].TokenValue, Span: lexer.Span{Start: span.Start + len(e[0].TokenValue), End: span.End}}
+		if span == (lexer.Span{}) {
+			literal.Span = span
+		}
+
+		return Node{Type: PrefixNode, Token: lexer.Minus, Value: e[0].TokenValue, Operands: []Node{literal}, Span: span}, true
+	default:
+		return Node{Type: NumberNode, Value: "-" + e[1].TokenValue, Span: span}, true
+	}
+}
+
+// isName reports whether s is read as a single Identifier.
+func isName(s string, tokens []lexer.Token) bool {
+	e, err := lexer.NewLexer(s, tokens, lexer.WithIdentifiers()).GetElementList()
+
+	return err == nil && len(e) == 1 && e[0].Token == lexer.Identifier
+}
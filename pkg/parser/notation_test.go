@@ -0,0 +1,187 @@
+package parser
+
+import (
+	"errors"
+	"math/rand/v2"
+	"reflect"
+	"testing"
+
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/lexer"
+)
+
+func TestPrinter_RPNAndPolish(t *testing.T) {
+	_, loose, implicit := printerParsers()
+
+	tests := []struct {
+		name       string
+		parser     Parser[int]
+		input      string
+		wantRPN    string
+		wantPolish string
+	}{
+		{name: "precedence", parser: loose, input: "(1+2)*3 - 4", wantRPN: "1 2 + 3 * 4 -", wantPolish: "- * + 1 2 3 4"},
+		{name: "right associative", parser: loose, input: "2^3^2", wantRPN: "2 3 2 ^ ^", wantPolish: "^ 2 ^ 3 2"},
+		{name: "unary minus", parser: loose, input: "2 - -x", wantRPN: "2 x u- -", wantPolish: "- 2 u- x"},
+		{name: "postfix", parser: loose, input: "3!%", wantRPN: "3 ! %", wantPolish: "% ! 3"},
+		{name: "call", parser: implicit, input: "max(1, 2x, max())", wantRPN: "1 2 x * max/0 max/3", wantPolish: "max/3 1 * 2 x max/0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := tt.parser.Parse(lex(t, tt.input))
+			if err != nil {
+				t.Fatalf("Parse() err=%v", err)
+			}
+
+			pr := NewPrinter(printerTokens, tt.parser.OperationGroups)
+
+			rpn, err := pr.RPN(n)
+			if err != nil || rpn != tt.wantRPN {
+				t.Fatalf("RPN() = (%q, %v), want %q", rpn, err, tt.wantRPN)
+			}
+
+			polish, err := pr.Polish(n)
+			if err != nil || polish != tt.wantPolish {
+				t.Fatalf("Polish() = (%q, %v), want %q", polish, err, tt.wantPolish)
+			}
+
+			// Reading either notation back gives an expression with the same value.
+			want, err := tt.parser.Eval(lex(t, tt.input))
+			if err != nil {
+				return
+			}
+
+			for _, parse := range []func() (Node, error){
+				func() (Node, error) { return ParseRPN(rpn, printerTokens, tt.parser.OperationGroups) },
+				func() (Node, error) { return ParsePolish(polish, printerTokens, tt.parser.OperationGroups) },
+			} {
+				n, err := parse()
+				if err != nil {
+					t.Fatalf("parsing %q err=%v", rpn, err)
+				}
+
+				elements, err := pr.Elements(n)
+				if err != nil {
+					t.Fatalf("Elements() err=%v", err)
+				}
+
+				if got, err := tt.parser.Eval(elements); err != nil || *got != *want {
+					t.Fatalf("Eval(%v) = (%v, %v), want %d", elements, got, err, *want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseRPN(t *testing.T) {
+	_, loose, _ := printerParsers()
+
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr error
+	}{
+		{name: "spaces", input: "  1\t2 +\n3 * ", want: "(1 + 2) * 3"},
+		{name: "aliases and literals", input: "0x10 1_000 ×", want: "16 * 1000"},
+		{name: "unary minus", input: "2 u- 2 ^", want: "(-2) ^ 2"},
+		{name: "call", input: "x 1 f/2", want: "f(x, 1)"},
+		{name: "negative number", input: "2 -3 ^ -0x10 -", want: "2 ^ -3 - -16"},
+		{name: "error - negative name", input: "-x", wantErr: errUnknownWord},
+		{name: "error - empty", input: " ", wantErr: errInvalidExpression},
+		{name: "error - too few operands", input: "1 +", wantErr: errInvalidExpression},
+		{name: "error - too many operands", input: "1 2", wantErr: errInvalidExpression},
+		{name: "error - unknown word", input: "1 2 $", wantErr: errUnknownWord},
+		{name: "error - not unary", input: "1 u*", wantErr: errUnknownWord},
+	}
+
+	pr := NewPrinter(printerTokens, loose.OperationGroups)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := ParseRPN(tt.input, printerTokens, loose.OperationGroups)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseRPN() err=%v wantErr=%v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				return
+			}
+
+			if got, err := pr.Format(n); err != nil || got != tt.want {
+				t.Fatalf("Format(ParseRPN()) = (%q, %v), want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+// TestParseRPN_NegativeLiteral checks that a negative number is read as a literal in a language without
+// a prefix minus.
+func TestParseRPN_NegativeLiteral(t *testing.T) {
+	p := newTestParser()
+
+	n, err := ParseRPN("3 -5 +", printerTokens, p.OperationGroups)
+	if err != nil {
+		t.Fatalf("ParseRPN() err=%v", err)
+	}
+
+	want := Node{Type: InfixNode, Token: lexer.Plus, Value: "+", Operands: []Node{
+		{Type: NumberNode, Value: "3"}, {Type: NumberNode, Value: "-5"},
+	}}
+
+	if !reflect.DeepEqual(withoutSpans(n), want) {
+		t.Fatalf("ParseRPN() got=%v want=%v", n, want)
+	}
+
+	if got, err := p.EvalNode(n); err != nil || *got != -2 {
+		t.Fatalf("EvalNode() = (%v, %v), want -2", got, err)
+	}
+}
+
+// TestParseRPN_RoundTrip checks that random trees survive being written in RPN and Polish notation.
+func TestParseRPN_RoundTrip(t *testing.T) {
+	tight, loose, _ := printerParsers()
+	r := rand.New(rand.NewPCG(3, 4))
+
+	for _, p := range []Parser[int]{tight, loose} {
+		pr := NewPrinter(printerTokens, p.OperationGroups)
+
+		for range 1000 {
+			want := randomNode(r, p, 4)
+
+			rpn, err := pr.RPN(want)
+			if err != nil {
+				t.Fatalf("RPN(%v) err=%v", want, err)
+			}
+
+			polish, err := pr.Polish(want)
+			if err != nil {
+				t.Fatalf("Polish(%v) err=%v", want, err)
+			}
+
+			fromRPN, err := ParseRPN(rpn, printerTokens, p.OperationGroups)
+			if err != nil || !reflect.DeepEqual(withoutValues(fromRPN), withoutValues(want)) {
+				t.Fatalf("ParseRPN(%q) = (%v, %v), want %v", rpn, fromRPN, err, want)
+			}
+
+			fromPolish, err := ParsePolish(polish, printerTokens, p.OperationGroups)
+			if err != nil || !reflect.DeepEqual(withoutValues(fromPolish), withoutValues(want)) {
+				t.Fatalf("ParsePolish(%q) = (%v, %v), want %v", polish, fromPolish, err, want)
+			}
+		}
+	}
+}
+
+// withoutValues returns n without Spans or the spellings of its operators, which differ between notations.
+func withoutValues(n Node) Node {
+	n = withoutSpans(n)
+	if n.Type != NumberNode && n.Type != IdentifierNode {
+		n.Value = ""
+	}
+
+	for i := range n.Operands {
+		n.Operands[i] = withoutValues(n.Operands[i])
+	}
+
+	return n
+}
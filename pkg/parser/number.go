@@ -0,0 +1,48 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseNumber converts the TokenValue of a Number element to a T.
+func parseNumber[T Number](s string) (T, error) {
+	var v T
+
+	switch p := any(&v).(type) {
+	case *int:
+		// The lexer writes other bases in decimal, so an e can only be an exponent, as in 1e6.
+		if strings.ContainsAny(s, "eE") {
+			return v, fmt.Errorf("%w: %s", errIntegerExponent, s)
+		}
+
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return v, fmt.Errorf("%w: %w", errInvalidNumber, err)
+		}
+
+		*p = n
+	case *float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return v, fmt.Errorf("%w: %w", errInvalidNumber, err)
+		}
+
+		*p = f
+	}
+
+	return v, nil
+}
+
+// formatNumber converts v to the TokenValue of a Number element, such that parseNumber(formatNumber(v)) == v.
+func formatNumber[T Number](v T) string {
+	switch v := any(v).(type) {
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	default:
+		panic("unreachable")
+	}
+}
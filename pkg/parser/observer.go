@@ -0,0 +1,71 @@
+package parser
+
+import "github.com/LaoZhuBaba/arithmetic_parser/pkg/lexer"
+
+// Observer is notified before and after the Parser applies each Operation, e.g. to count how often each
+// operator is used or to log slow evaluations.  Observers must not modify Operands.
+type Observer[T Number] interface {
+	BeforeOperation(e OperationEvent[T])
+	AfterOperation(e OperationEvent[T])
+}
+
+// OperationEvent describes an Operation being applied to Operands, of which there are two for an infix
+// Operation and one for a prefix or postfix Operation.  Result and Err are only set after the Operation.
+type OperationEvent[T Number] struct {
+	Description string
+	TokenId     lexer.TokenId
+	Operands    []T
+	Result      T
+	Err         error
+}
+
+// WithObserver notifies o of every Operation applied.  The Number type of o must match the Number type
+// of the Parser.
+func WithObserver[T Number](o Observer[T]) Option {
+	return func(opts *options) {
+		opts.observer = o
+	}
+}
+
+// applyFn returns op.Fn(a, b), notifying the Parser's Observer, if it has one, and checking its Limits.
+func (p Parser[T]) applyFn(op *Operation[T], a, b T) (T, error) {
+	return p.apply(op, []T{a, b}, func() (T, error) { return op.Fn(a, b) })
+}
+
+// applyUnaryFn returns op.UnaryFn(a), notifying the Parser's Observer, if it has one, and checking its Limits.
+func (p Parser[T]) applyUnaryFn(op *Operation[T], a T) (T, error) {
+	return p.apply(op, []T{a}, func() (T, error) { return op.UnaryFn(a) })
+}
+
+func (p Parser[T]) apply(op *Operation[T], operands []T, fn func() (T, error)) (T, error) {
+	if err := p.spend(); err != nil {
+		return 0, err
+	}
+
+	var (
+		result T
+		err    error
+	)
+
+	if o, _ := p.observer.(Observer[T]); o != nil {
+		result, err = observe(o, op, operands, fn)
+	} else {
+		result, err = fn()
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result, p.checkMagnitude(result)
+}
+
+func observe[T Number](o Observer[T], op *Operation[T], operands []T, fn func() (T, error)) (T, error) {
+	e := OperationEvent[T]{Description: op.Description, TokenId: op.TokenId, Operands: operands}
+	o.BeforeOperation(e)
+
+	e.Result, e.Err = fn()
+	o.AfterOperation(e)
+
+	return e.Result, e.Err
+}
@@ -0,0 +1,57 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/lexer"
+)
+
+// recorder is an Observer that records every event as a string.
+type recorder struct {
+	events []string
+}
+
+func (r *recorder) BeforeOperation(e OperationEvent[int]) {
+	r.events = append(r.events, fmt.Sprintf("before %s %v", e.Description, e.Operands))
+}
+
+func (r *recorder) AfterOperation(e OperationEvent[int]) {
+	r.events = append(r.events, fmt.Sprintf("after %s %v = %d, %v", e.Description, e.Operands, e.Result, e.Err))
+}
+
+func TestParser_Observer(t *testing.T) {
+	base := newTestParser()
+
+	var r recorder
+
+	p := mustNewParser(base.Operations, base.OperationGroups, WithObserver[int](&r))
+
+	// 3! + 8/0
+	elements := lexer.ElementList{
+		{Token: lexer.Number, TokenValue: "3"},
+		{Token: lexer.Factorial, TokenValue: "!"},
+		{Token: lexer.Plus, TokenValue: "+"},
+		{Token: lexer.Number, TokenValue: "8"},
+		{Token: lexer.Divide, TokenValue: "/"},
+		{Token: lexer.Number, TokenValue: "0"},
+	}
+
+	_, err := p.Eval(elements)
+	if !errors.Is(err, ErrEvaluation) {
+		t.Fatalf("Eval() err=%v, want ErrEvaluation", err)
+	}
+
+	want := []string{
+		"before Factorial [3]",
+		"after Factorial [3] = 6, <nil>",
+		"before Divide [8 0]",
+		"after Divide [8 0] = 0, division by zero",
+	}
+
+	if !reflect.DeepEqual(r.events, want) {
+		t.Fatalf("events = %q, want %q", r.events, want)
+	}
+}
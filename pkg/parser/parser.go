@@ -1,18 +1,75 @@
 package parser
 
 import (
+	"context"
 	"fmt"
-	"strconv"
+	"slices"
 
 	"github.com/LaoZhuBaba/arithmetic_parser/pkg/lexer"
 )
 
-func NewParser(operations []Operation, opGroups []OperationGroup) (p Parser) {
-	p = Parser{Operations: operations, OperationGroups: opGroups}
-	return p
+// NewParser returns a Parser for operations and opGroups, or an error describing the first problem found
+// with them: an OperationGroup token with no matching Operation, two Operations with the same TokenId, an
+// Operation in more than one group or without the Fn its group's Fixity needs, OperationGroups that
+// aren't listed in increasing order of Precedence, or an option such as WithBuiltins given values of
+// another Number type.
+func NewParser[T Number](operations []Operation[T], opGroups []OperationGroup, opts ...Option) (Parser[T], error) {
+	p := Parser[T]{Operations: operations, OperationGroups: opGroups}
+	for _, opt := range opts {
+		opt(&p.options)
+	}
+
+	if err := p.validate(); err != nil {
+		return Parser[T]{}, err
+	}
+
+	return p, nil
+}
+
+// WithImplicitMultiplication treats juxtaposition as multiplication: wherever a Number, Identifier or
+// RParen is immediately followed by an LParen or Identifier, an element with TokenId tok is inserted
+// between them, so 2(3+4) is evaluated as 2*(3+4).  Passing lexer.Multiply gives implicit multiplication
+// the same precedence as *.  Passing lexer.ImplicitMultiply lets it bind more tightly, provided the
+// Operations and OperationGroups include an entry for lexer.ImplicitMultiply.
+func WithImplicitMultiplication(tok lexer.TokenId) Option {
+	return func(o *options) {
+		o.implicitMultiply = tok
+	}
+}
+
+// WithEnvironment makes the variables held by env available to expressions.  The Number type
+// of env must match the Number type of the Parser.
+func WithEnvironment[T Number](env *Environment[T]) Option {
+	return func(o *options) {
+		o.environment = env
+	}
 }
 
-func (p Parser) getOperationByTokenId(t lexer.TokenId) (*Operation, error) {
+// WithMaxCallDepth limits how deeply Function calls may be nested, which stops runaway recursion.
+// The default is DefaultMaxCallDepth.
+func WithMaxCallDepth(n int) Option {
+	return func(o *options) {
+		o.maxCallDepth = n
+	}
+}
+
+// WithBuiltins makes fns available to expressions, which call them by name, e.g. sqrt(2).  A Function
+// defined in the Environment takes priority over a BuiltinFunction of the same name.
+func WithBuiltins[T Number](fns []BuiltinFunction[T]) Option {
+	return func(o *options) {
+		o.builtins = fns
+	}
+}
+
+// WithConstants makes the named values available to expressions.  Unlike the variables of an Environment,
+// constants take priority over any variable of the same name and can't be assigned to or used as parameters.
+func WithConstants[T Number](constants map[string]T) Option {
+	return func(o *options) {
+		o.constants = constants
+	}
+}
+
+func (p Parser[T]) getOperationByTokenId(t lexer.TokenId) (*Operation[T], error) {
 	for _, op := range p.Operations {
 		if op.TokenId == t {
 			return &op, nil
@@ -23,12 +80,44 @@ func (p Parser) getOperationByTokenId(t lexer.TokenId) (*Operation, error) {
 }
 
 // Eval accepts a list of elements representing an arithmetic expression
-// and returns the result as a pointer to an int.
-func (p Parser) Eval(e lexer.ElementList) (_ *int, err error) {
+// and returns the result as a pointer to a T.
+func (p Parser[T]) Eval(e lexer.ElementList) (*T, error) {
+	return p.EvalContext(context.Background(), e)
+}
+
+// EvalContext is Eval with a Context, which stops the evaluation with ctx.Err() if it is cancelled.
+func (p Parser[T]) EvalContext(ctx context.Context, e lexer.ElementList) (*T, error) {
+	p, err := p.start(ctx, e)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.eval(e)
+}
+
+// eval evaluates e as part of an evaluation started by EvalContext or EvalStatementsContext.
+func (p Parser[T]) eval(e lexer.ElementList) (_ *T, err error) {
 	// Make a copy of the slice so we can modify it without affecting the original
-	// Fuzz testing requires that the slice be immutable.
-	elementList := make(lexer.ElementList, len(e))
-	copy(elementList, e)
+	// Fuzz testing requires that the slice be immutable.  Comments are dropped from the copy.
+	elementList := make(lexer.ElementList, 0, len(e))
+	for _, element := range e {
+		if element.Token != lexer.Comment {
+			elementList = append(elementList, element)
+		}
+	}
+
+	// Calls are evaluated first so that implicit multiplication doesn't treat f(2) as f*(2).
+	elementList, err = p.evalCalls(elementList)
+	if err != nil {
+		return nil, err
+	}
+
+	elementList = p.insertImplicitMultiplication(elementList)
+
+	elementList, err = p.resolveIdentifiers(elementList)
+	if err != nil {
+		return nil, err
+	}
 
 	// Evaluate parenthetical expressions first
 	elementList, err = p.evalParen(elementList)
@@ -36,12 +125,11 @@ func (p Parser) Eval(e lexer.ElementList) (_ *int, err error) {
 		return nil, err
 	}
 
-	// operationGroups is the map of precedence levels to the operators that can be used in that level
-	// Each key is a precedence level that refers to a group of operators that have the same precedence
-	// and associativity.  For example, multiplication and division share the same precedence level called
-	// "multiplyDivide" and they are both left associative.
+	// Each OperationGroup refers to a group of operators that have the same precedence, associativity
+	// and fixity.  For example, multiplication and division share the same precedence level called
+	// "multiplyDivide" and they are both left associative infix operators.
 	for _, group := range p.OperationGroups {
-		elementList, err = p.evalArithmetic(elementList, group.Precedence)
+		elementList, err = p.evalArithmetic(elementList, group)
 		if err != nil {
 			return nil, err
 		}
@@ -52,7 +140,7 @@ func (p Parser) Eval(e lexer.ElementList) (_ *int, err error) {
 		return nil, fmt.Errorf("%w: %v", errInvalidExpression, elementList)
 	}
 
-	result, err := strconv.Atoi(elementList[0].TokenValue)
+	result, err := parseNumber[T](elementList[0].TokenValue)
 	if err != nil {
 		return nil, err
 	}
@@ -60,8 +148,36 @@ func (p Parser) Eval(e lexer.ElementList) (_ *int, err error) {
 	return &result, nil
 }
 
+// insertImplicitMultiplication returns elementList with p.implicitMultiply inserted between juxtaposed operands.
+// Applying it to a list that has already been processed leaves the list unchanged.
+func (p Parser[T]) insertImplicitMultiplication(elementList lexer.ElementList) lexer.ElementList {
+	if p.implicitMultiply == lexer.NullToken {
+		return elementList
+	}
+
+	result := make(lexer.ElementList, 0, len(elementList))
+
+	for i, element := range elementList {
+		if i > 0 && endsOperand(elementList[i-1].Token) && startsOperand(element.Token) {
+			result = append(result, lexer.Element{Token: p.implicitMultiply})
+		}
+
+		result = append(result, element)
+	}
+
+	return result
+}
+
+func endsOperand(tok lexer.TokenId) bool {
+	return tok == lexer.Number || tok == lexer.Identifier || tok == lexer.RParen
+}
+
+func startsOperand(tok lexer.TokenId) bool {
+	return tok == lexer.LParen || tok == lexer.Identifier
+}
+
 // evalParen reduces parenthetical expressions to numbers, calling Eval() for subexpressions
-func (p Parser) evalParen(elementList lexer.ElementList) (lexer.ElementList, error) {
+func (p Parser[T]) evalParen(elementList lexer.ElementList) (lexer.ElementList, error) {
 	// Iterate until every parenthetical expression has been reduced to a number
 	for {
 		// It's not an error to find no left parenthesis.  Unbalanced parentheses are handled in findRParen()
@@ -75,12 +191,16 @@ func (p Parser) evalParen(elementList lexer.ElementList) (lexer.ElementList, err
 			return nil, err
 		}
 		// Submit the expression inside the parentheses for evaluation
-		val, err := p.Eval(elementList[lParenIdx+1 : rParenIdx])
+		val, err := p.eval(elementList[lParenIdx+1 : rParenIdx])
 		if err != nil {
 			return nil, err
 		}
 		// Build a number element with the result of the evaluation
-		newElement := lexer.ElementList{{Token: lexer.Number, TokenValue: fmt.Sprintf("%d", *val)}}
+		newElement := lexer.ElementList{{
+			Token:      lexer.Number,
+			TokenValue: formatNumber(*val),
+			Span:       spanOf(elementList[lParenIdx : rParenIdx+1]),
+		}}
 		// Replace the parentheses with the evaluated expression
 		elementList = append(
 			elementList[:lParenIdx],
@@ -91,69 +211,338 @@ func (p Parser) evalParen(elementList lexer.ElementList) (lexer.ElementList, err
 	return elementList, nil
 }
 
-// evalArithmetic reduces arithmetic expressions to a single number element, calling Eval() for subexpressions.
-func (p Parser) evalArithmetic(elementList lexer.ElementList, precedence precedence) (lexer.ElementList, error) {
-	for {
-		var exprVal, lVal, rVal, idx int
+// evalArithmetic reduces every operation belonging to group to a number element.
+func (p Parser[T]) evalArithmetic(elementList lexer.ElementList, group OperationGroup) (lexer.ElementList, error) {
+	return p.reduce(elementList, group, reductions{infix: p.evalInfix, postfix: p.evalPostfix, prefix: p.evalPrefix})
+}
+
+// reduction replaces the operator at idx and its operands, which are Number elements, with a single Number element.
+type reduction func(idx int, elementList lexer.ElementList) (lexer.ElementList, error)
 
-		var tok lexer.TokenId
+// reductions hold the reduction for each Fixity.  Eval's reductions apply Operations, and Parse's build Nodes,
+// so that both see the same structure.
+type reductions struct {
+	infix, postfix, prefix reduction
+}
+
+// reduce finds every operator belonging to group, in the order given by the group's Associativity, and
+// reduces each with r.
+func (p Parser[T]) reduce(elementList lexer.ElementList, group OperationGroup, r reductions) (lexer.ElementList, error) {
+	if group.Fixity == Prefix {
+		return p.reducePrefixGroup(elementList, group, r.prefix)
+	}
 
-		switch p.OperationGroups[precedence].Associativity {
+	for {
+		var (
+			tok lexer.TokenId
+			idx int
+			err error
+		)
+
+		switch group.Associativity {
 		case RightAssociative:
 			// Get the index of the next operator and the TokenId
-			tok, idx = elementList.FindRightOperator(p.OperationGroups[precedence].Tokens)
+			tok, idx = elementList.FindRightOperator(group.Tokens)
 
-		case LeftAssociative:
+		case LeftAssociative, NonAssociative:
 			// Get the index of the next operator and the TokenId
-			tok, idx = elementList.FindLeftOperator(p.OperationGroups[precedence].Tokens)
+			tok, idx = elementList.FindLeftOperator(group.Tokens)
 		}
 
 		if tok == lexer.NullToken {
 			break
 		}
 
-		// Get the elements that make up the expression: [number, operator, number]
-		subExpr, err := p.getOperatorElements(idx, elementList)
-		if err != nil {
-			return nil, err
+		if group.Associativity == NonAssociative {
+			if err := p.checkNotChained(idx, elementList, group); err != nil {
+				return nil, err
+			}
 		}
 
-		lVal, err = strconv.Atoi(subExpr[0].TokenValue)
+		if group.Fixity == Postfix {
+			elementList, err = r.postfix(idx, elementList)
+			if err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		// A prefix operator that binds less tightly than this group may still start the right operand, as in 2^-1.
+		elementList, err = p.reducePrefixOperand(idx+1, elementList, r.prefix)
 		if err != nil {
 			return nil, err
 		}
 
-		rVal, err = strconv.Atoi(subExpr[2].TokenValue)
+		elementList, err = r.infix(idx, elementList)
 		if err != nil {
 			return nil, err
 		}
+	}
+
+	return elementList, nil
+}
+
+// evalInfix applies the infix operator at idx to the number elements either side of it.
+func (p Parser[T]) evalInfix(idx int, elementList lexer.ElementList) (lexer.ElementList, error) {
+	// Get the elements that make up the expression: [number, operator, number]
+	subExpr, err := p.getOperatorElements(idx, elementList)
+	if err != nil {
+		return nil, err
+	}
+
+	lVal, err := parseNumber[T](subExpr[0].TokenValue)
+	if err != nil {
+		return nil, err
+	}
+
+	rVal, err := parseNumber[T](subExpr[2].TokenValue)
+	if err != nil {
+		return nil, err
+	}
+
+	op, err := p.infixOperation(subExpr[1].Token)
+	if err != nil {
+		return nil, err
+	}
+
+	exprVal, err := p.applyFn(op, lVal, rVal)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrEvaluation, err)
+	}
+
+	span := spanOf(subExpr)
+	p.record(Step[T]{
+		Description: op.Description,
+		Expression:  infixExpression(subExpr),
+		Operands:    []T{lVal, rVal},
+		Result:      exprVal,
+		Span:        span,
+	})
+
+	// remainder is the slice of elements after the expression being evaluated.
+	// idx+2 is used because idx is the index of the operator TokenId, so idx+1 is
+	// the second number TokenId, and idx+2 is the start of anything that follows.
+	remainder := make(lexer.ElementList, len(elementList[idx+2:]))
+	copy(remainder, elementList[idx+2:])
+	// idx-1 is the index of the left operand so we are taking everything before the
+	// expression and appending the result of the expression.
+	elementList = append(elementList[:idx-1], lexer.Element{Token: lexer.Number, TokenValue: formatNumber(exprVal), Span: span})
 
-		op, err := p.getOperationByTokenId(tok)
+	return append(elementList, remainder...), nil
+}
+
+// infixOperation returns the Operation for tok, which must be usable as an infix operator.
+func (p Parser[T]) infixOperation(tok lexer.TokenId) (*Operation[T], error) {
+	op, err := p.getOperationByTokenId(tok)
+	if err != nil {
+		return nil, err
+	}
+
+	if op.Fn == nil {
+		return nil, fmt.Errorf("%w: %s cannot be used as an infix operator", errInvalidOperation, op.Description)
+	}
+
+	return op, nil
+}
+
+// checkNotChained returns an error if the operand following the operator at idx is itself followed by
+// another operator from group, as in a<b<c.  Because the leftmost operator is always reduced first and
+// higher precedence groups have already been reduced, this catches every chain in the group.
+func (p Parser[T]) checkNotChained(idx int, elementList lexer.ElementList, group OperationGroup) error {
+	// The operand after an infix operator is at idx+1 so the next operator would be at idx+2.
+	// A postfix operator has no right operand, so the next operator would follow it directly.
+	next := idx + 2
+	if group.Fixity == Postfix {
+		next = idx + 1
+	}
+
+	if next >= len(elementList) {
+		return nil
+	}
+
+	tok, _ := lexer.ElementList{elementList[next]}.FindLeftOperator(group.Tokens)
+	if tok == lexer.NullToken {
+		return nil
+	}
+
+	first, err := p.getOperationByTokenId(elementList[idx].Token)
+	if err != nil {
+		return err
+	}
+
+	second, err := p.getOperationByTokenId(tok)
+	if err != nil {
+		return err
+	}
+
+	return fmt.Errorf("%w: %s followed by %s", errNonAssociative, first.Description, second.Description)
+}
+
+// evalPostfix applies the postfix operator at idx to the number element immediately before it.
+func (p Parser[T]) evalPostfix(idx int, elementList lexer.ElementList) (lexer.ElementList, error) {
+	op, err := p.unaryOperation(idx, elementList, Postfix)
+	if err != nil {
+		return nil, err
+	}
+
+	val, err := parseNumber[T](elementList[idx-1].TokenValue)
+	if err != nil {
+		return nil, err
+	}
+
+	exprVal, err := p.applyUnaryFn(op, val)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrEvaluation, err)
+	}
+
+	span := spanOf(elementList[idx-1 : idx+1])
+	p.record(Step[T]{
+		Description: op.Description,
+		Expression:  elementList[idx-1].TokenValue + elementList[idx].TokenValue,
+		Operands:    []T{val},
+		Result:      exprVal,
+		Span:        span,
+	})
+
+	// Replace [number, operator] with the result, keeping everything after the operator.
+	remainder := make(lexer.ElementList, len(elementList[idx+1:]))
+	copy(remainder, elementList[idx+1:])
+	elementList = append(elementList[:idx-1], lexer.Element{Token: lexer.Number, TokenValue: formatNumber(exprVal), Span: span})
+
+	return append(elementList, remainder...), nil
+}
+
+// unaryOperation returns the Operation for the Postfix or Prefix operator at idx, checking that it can be
+// used that way and that it has a Number element to apply to.
+func (p Parser[T]) unaryOperation(idx int, elementList lexer.ElementList, fixity Fixity) (*Operation[T], error) {
+	op, err := p.getOperationByTokenId(elementList[idx].Token)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case fixity == Postfix && op.UnaryFn == nil:
+		return nil, fmt.Errorf("%w: %s cannot be used as a postfix operator", errInvalidOperation, op.Description)
+	case fixity == Postfix && (idx < 1 || elementList[idx-1].Token != lexer.Number):
+		return nil, fmt.Errorf("%w: before %s: expected Number", errInvalidTokenId, op.Description)
+	case fixity == Prefix && op.UnaryFn == nil:
+		return nil, fmt.Errorf("%w: %s cannot be used as a prefix operator", errInvalidOperation, op.Description)
+	case fixity == Prefix && (idx+1 >= len(elementList) || elementList[idx+1].Token != lexer.Number):
+		return nil, fmt.Errorf("%w: after %s: expected Number", errInvalidTokenId, op.Description)
+	}
+
+	return op, nil
+}
+
+// reducePrefixGroup reduces every operator belonging to the Prefix group that doesn't follow an operand,
+// working from right to left so that --3 is -(-3).
+func (p Parser[T]) reducePrefixGroup(elementList lexer.ElementList, group OperationGroup, prefix reduction) (lexer.ElementList, error) {
+	var err error
+
+	for idx := len(elementList) - 1; idx >= 0; idx-- {
+		if !slices.Contains(group.Tokens, elementList[idx].Token) || p.followsOperand(idx, elementList) {
+			continue
+		}
+
+		elementList, err = prefix(idx, elementList)
 		if err != nil {
 			return nil, err
 		}
+	}
+
+	return elementList, nil
+}
 
-		exprVal, err = op.Fn(lVal, rVal)
+// reducePrefixOperand reduces any prefix operators at idx, and immediately after it, with the Number that follows them.
+func (p Parser[T]) reducePrefixOperand(idx int, elementList lexer.ElementList, prefix reduction) (lexer.ElementList, error) {
+	end := idx
+	for end < len(elementList) && p.isPrefixOperator(elementList[end].Token) {
+		end++
+	}
+
+	var err error
+
+	for i := end - 1; i >= idx; i-- {
+		elementList, err = prefix(i, elementList)
 		if err != nil {
 			return nil, err
 		}
-
-		// remainder is the slice of elements after the expression being evaluated.
-		// idx+2 is used because idx is the index of the operator TokenId, so idx+1 is
-		// the second number TokenId, and idx+2 is the start of anything that follows.
-		remainder := make(lexer.ElementList, len(elementList[idx+2:]))
-		copy(remainder, elementList[idx+2:])
-		// idx-1 is the index of the left operand so we are taking everything before the
-		// expression and appending the result of the expression.
-		elementList = append(elementList[:idx-1], lexer.Element{Token: lexer.Number, TokenValue: fmt.Sprintf("%d", exprVal)})
-		elementList = append(elementList, remainder...)
 	}
 
 	return elementList, nil
 }
 
+// evalPrefix applies the prefix operator at idx to the number element immediately after it.
+func (p Parser[T]) evalPrefix(idx int, elementList lexer.ElementList) (lexer.ElementList, error) {
+	op, err := p.unaryOperation(idx, elementList, Prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	val, err := parseNumber[T](elementList[idx+1].TokenValue)
+	if err != nil {
+		return nil, err
+	}
+
+	exprVal, err := p.applyUnaryFn(op, val)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrEvaluation, err)
+	}
+
+	span := spanOf(elementList[idx : idx+2])
+	p.record(Step[T]{
+		Description: op.Description,
+		Expression:  elementList[idx].TokenValue + elementList[idx+1].TokenValue,
+		Operands:    []T{val},
+		Result:      exprVal,
+		Span:        span,
+	})
+
+	// Replace [operator, number] with the result, keeping everything after the number.
+	remainder := make(lexer.ElementList, len(elementList[idx+2:]))
+	copy(remainder, elementList[idx+2:])
+	elementList = append(elementList[:idx], lexer.Element{Token: lexer.Number, TokenValue: formatNumber(exprVal), Span: span})
+
+	return append(elementList, remainder...), nil
+}
+
+// followsOperand reports whether the element at idx comes straight after an operand, in which case an
+// operator at idx is infix or postfix rather than prefix.  Postfix operators end an operand, so in 50%-3
+// the - is subtraction.
+func (p Parser[T]) followsOperand(idx int, elementList lexer.ElementList) bool {
+	if idx == 0 {
+		return false
+	}
+
+	prev := elementList[idx-1].Token
+
+	return prev == lexer.Number || p.groupOf(prev, Postfix) >= 0
+}
+
+// isPrefixOperator reports whether tok belongs to a Prefix OperationGroup.
+func (p Parser[T]) isPrefixOperator(tok lexer.TokenId) bool {
+	return p.groupOf(tok, Prefix) >= 0
+}
+
+// groupOf returns the index of the OperationGroup with the given fixity that contains tok, or -1.
+func (p Parser[T]) groupOf(tok lexer.TokenId, fixity Fixity) int {
+	return groupIndex(p.OperationGroups, tok, fixity)
+}
+
+// groupIndex returns the index of the OperationGroup in groups with the given fixity that contains tok, or -1.
+func groupIndex(groups []OperationGroup, tok lexer.TokenId, fixity Fixity) int {
+	for i, group := range groups {
+		if group.Fixity == fixity && slices.Contains(group.Tokens, tok) {
+			return i
+		}
+	}
+
+	return -1
+}
+
 // getOperatorElements returns the elements that make up an operator expression: [number, operator, number]
-func (p Parser) getOperatorElements(idx int, elementList lexer.ElementList) (subExp lexer.ElementList, err error) {
+func (p Parser[T]) getOperatorElements(idx int, elementList lexer.ElementList) (subExp lexer.ElementList, err error) {
 	// elements[idx] should be an operator TokenId so there must be a character before and after it
 	if idx < 1 || idx >= len(elementList)-1 {
 		return nil, fmt.Errorf("%w: with index %d and elements: %v", errIndexOutOfRange, idx, elementList)
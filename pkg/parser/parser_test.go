@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
 	"math"
 	"testing"
@@ -8,8 +9,8 @@ import (
 	"github.com/LaoZhuBaba/arithmetic_parser/pkg/lexer"
 )
 
-func newTestParser() Parser {
-	operations := []Operation{
+func newTestParser() Parser[int] {
+	operations := []Operation[int]{
 		{Description: "Plus", TokenId: lexer.Plus, Fn: func(a, b int) (int, error) { return a + b, nil }},
 		{Description: "Minus", TokenId: lexer.Minus, Fn: func(a, b int) (int, error) { return a - b, nil }},
 		{Description: "Multiply", TokenId: lexer.Multiply, Fn: func(a, b int) (int, error) { return a * b, nil }},
@@ -22,13 +23,35 @@ func newTestParser() Parser {
 		{Description: "Exponent", TokenId: lexer.Exponent, Fn: func(a, b int) (int, error) {
 			return int(math.Pow(float64(a), float64(b))), nil
 		}},
+		{Description: "Factorial", TokenId: lexer.Factorial, UnaryFn: func(a int) (int, error) {
+			if a < 0 {
+				return 0, fmt.Errorf("factorial of negative number")
+			}
+			result := 1
+			for i := 2; i <= a; i++ {
+				result *= i
+			}
+			return result, nil
+		}},
+		{Description: "Percent", TokenId: lexer.Percent, UnaryFn: func(a int) (int, error) { return a / 100, nil }},
 	}
 	opGroups := []OperationGroup{
+		{Tokens: []lexer.TokenId{lexer.Factorial, lexer.Percent}, Precedence: PrecedencePostfix, Associativity: LeftAssociative, Fixity: Postfix},
 		{Tokens: []lexer.TokenId{lexer.Exponent}, Precedence: PrecedenceExponent, Associativity: RightAssociative},
 		{Tokens: []lexer.TokenId{lexer.Multiply, lexer.Divide}, Precedence: PrecedenceMultiplyDivide, Associativity: LeftAssociative},
 		{Tokens: []lexer.TokenId{lexer.Plus, lexer.Minus}, Precedence: PrecedencePlusMinus, Associativity: LeftAssociative},
 	}
-	return NewParser(operations, opGroups)
+	return mustNewParser(operations, opGroups)
+}
+
+// mustNewParser is NewParser for configurations that are known to be valid.
+func mustNewParser[T Number](operations []Operation[T], opGroups []OperationGroup, opts ...Option) Parser[T] {
+	p, err := NewParser(operations, opGroups, opts...)
+	if err != nil {
+		panic(err)
+	}
+
+	return p
 }
 
 func ptrInt(v int) *int { return &v }
@@ -131,6 +154,16 @@ func TestParser_Eval(t *testing.T) {
 			want:    ptrInt(42),
 			wantErr: false,
 		},
+		{
+			name: "comments are ignored",
+			elements: lexer.ElementList{
+				{Token: lexer.Comment, TokenValue: "/* answer */"},
+				{Token: lexer.Number, TokenValue: "42"},
+				{Token: lexer.Comment, TokenValue: "# done"},
+			},
+			want:    ptrInt(42),
+			wantErr: false,
+		},
 		{
 			name: "precedence: multiply before plus",
 			elements: lexer.ElementList{
@@ -179,6 +212,70 @@ func TestParser_Eval(t *testing.T) {
 			want:    nil,
 			wantErr: true,
 		},
+		{
+			name: "postfix factorial",
+			elements: lexer.ElementList{
+				{Token: lexer.Number, TokenValue: "5"},
+				{Token: lexer.Factorial, TokenValue: "!"},
+			},
+			want:    ptrInt(120),
+			wantErr: false,
+		},
+		{
+			name: "postfix binds tighter than exponent: 2^3!",
+			elements: lexer.ElementList{
+				{Token: lexer.Number, TokenValue: "2"},
+				{Token: lexer.Exponent, TokenValue: "^"},
+				{Token: lexer.Number, TokenValue: "3"},
+				{Token: lexer.Factorial, TokenValue: "!"},
+			},
+			want:    ptrInt(64),
+			wantErr: false,
+		},
+		{
+			name: "repeated postfix: 3!!",
+			elements: lexer.ElementList{
+				{Token: lexer.Number, TokenValue: "3"},
+				{Token: lexer.Factorial, TokenValue: "!"},
+				{Token: lexer.Factorial, TokenValue: "!"},
+			},
+			want:    ptrInt(720),
+			wantErr: false,
+		},
+		{
+			name: "postfix after parentheses: (1+199)%+1",
+			elements: lexer.ElementList{
+				{Token: lexer.LParen, TokenValue: "("},
+				{Token: lexer.Number, TokenValue: "1"},
+				{Token: lexer.Plus, TokenValue: "+"},
+				{Token: lexer.Number, TokenValue: "199"},
+				{Token: lexer.RParen, TokenValue: ")"},
+				{Token: lexer.Percent, TokenValue: "%"},
+				{Token: lexer.Plus, TokenValue: "+"},
+				{Token: lexer.Number, TokenValue: "1"},
+			},
+			want:    ptrInt(3),
+			wantErr: false,
+		},
+		{
+			name: "postfix without operand errors",
+			elements: lexer.ElementList{
+				{Token: lexer.Factorial, TokenValue: "!"},
+				{Token: lexer.Number, TokenValue: "5"},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "postfix operator used as infix errors",
+			elements: lexer.ElementList{
+				{Token: lexer.Number, TokenValue: "5"},
+				{Token: lexer.Percent, TokenValue: "%"},
+				{Token: lexer.Number, TokenValue: "2"},
+			},
+			want:    nil,
+			wantErr: true,
+		},
 		{
 			name: "unmatched parentheses errors",
 			elements: lexer.ElementList{
@@ -207,3 +304,378 @@ func TestParser_Eval(t *testing.T) {
 		})
 	}
 }
+
+func TestParser_ImplicitMultiplication(t *testing.T) {
+	base := newTestParser()
+	operations := append(base.Operations, Operation[int]{
+		Description: "ImplicitMultiply", TokenId: lexer.ImplicitMultiply, Fn: func(a, b int) (int, error) { return a * b, nil },
+	})
+	opGroups := []OperationGroup{
+		base.OperationGroups[0],
+		base.OperationGroups[1],
+		{Tokens: []lexer.TokenId{lexer.ImplicitMultiply}, Precedence: PrecedenceImplicitMultiply, Associativity: LeftAssociative},
+		base.OperationGroups[2],
+		base.OperationGroups[3],
+	}
+
+	// 12/2(3)
+	divideThenParen := lexer.ElementList{
+		{Token: lexer.Number, TokenValue: "12"},
+		{Token: lexer.Divide, TokenValue: "/"},
+		{Token: lexer.Number, TokenValue: "2"},
+		{Token: lexer.LParen, TokenValue: "("},
+		{Token: lexer.Number, TokenValue: "3"},
+		{Token: lexer.RParen, TokenValue: ")"},
+	}
+	// (1+1)(2+3)
+	parenParen := lexer.ElementList{
+		{Token: lexer.LParen, TokenValue: "("},
+		{Token: lexer.Number, TokenValue: "1"},
+		{Token: lexer.Plus, TokenValue: "+"},
+		{Token: lexer.Number, TokenValue: "1"},
+		{Token: lexer.RParen, TokenValue: ")"},
+		{Token: lexer.LParen, TokenValue: "("},
+		{Token: lexer.Number, TokenValue: "2"},
+		{Token: lexer.Plus, TokenValue: "+"},
+		{Token: lexer.Number, TokenValue: "3"},
+		{Token: lexer.RParen, TokenValue: ")"},
+	}
+	// 2x
+	numberIdentifier := lexer.ElementList{
+		{Token: lexer.Number, TokenValue: "2"},
+		{Token: lexer.Identifier, TokenValue: "x"},
+	}
+
+	tests := []struct {
+		name     string
+		parser   Parser[int]
+		elements lexer.ElementList
+		want     *int
+		wantErr  bool
+	}{
+		{
+			name:     "disabled by default",
+			parser:   mustNewParser(operations, opGroups),
+			elements: divideThenParen,
+			want:     nil,
+			wantErr:  true,
+		},
+		{
+			name:     "same precedence as multiply",
+			parser:   mustNewParser(operations, opGroups, WithImplicitMultiplication(lexer.Multiply)),
+			elements: divideThenParen,
+			want:     ptrInt(18),
+			wantErr:  false,
+		},
+		{
+			name:     "tighter than multiply",
+			parser:   mustNewParser(operations, opGroups, WithImplicitMultiplication(lexer.ImplicitMultiply)),
+			elements: divideThenParen,
+			want:     ptrInt(2),
+			wantErr:  false,
+		},
+		{
+			name:     "closing then opening parenthesis",
+			parser:   mustNewParser(operations, opGroups, WithImplicitMultiplication(lexer.Multiply)),
+			elements: parenParen,
+			want:     ptrInt(10),
+			wantErr:  false,
+		},
+		{
+			name:     "identifiers are multiplied but must be defined",
+			parser:   mustNewParser(operations, opGroups, WithImplicitMultiplication(lexer.Multiply)),
+			elements: numberIdentifier,
+			want:     nil,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.parser.Eval(tt.elements)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Eval() err=%v wantErr=%v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got == nil || *got != *tt.want {
+				t.Fatalf("Eval() got=%v want=%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParser_NonAssociative(t *testing.T) {
+	base := newTestParser()
+	// Declare plus and minus non-associative so that chains of them are rejected.
+	opGroups := []OperationGroup{
+		base.OperationGroups[0],
+		base.OperationGroups[1],
+		base.OperationGroups[2],
+		{Tokens: []lexer.TokenId{lexer.Plus, lexer.Minus}, Precedence: PrecedencePlusMinus, Associativity: NonAssociative},
+	}
+	p := mustNewParser(base.Operations, opGroups)
+
+	tests := []struct {
+		name     string
+		elements lexer.ElementList
+		want     *int
+		wantErr  bool
+	}{
+		{
+			name: "single operator is allowed",
+			elements: lexer.ElementList{
+				{Token: lexer.Number, TokenValue: "5"},
+				{Token: lexer.Minus, TokenValue: "-"},
+				{Token: lexer.Number, TokenValue: "3"},
+			},
+			want:    ptrInt(2),
+			wantErr: false,
+		},
+		{
+			name: "operands from higher precedence groups are allowed",
+			elements: lexer.ElementList{
+				{Token: lexer.Number, TokenValue: "2"},
+				{Token: lexer.Multiply, TokenValue: "*"},
+				{Token: lexer.Number, TokenValue: "3"},
+				{Token: lexer.Plus, TokenValue: "+"},
+				{Token: lexer.Number, TokenValue: "4"},
+				{Token: lexer.Multiply, TokenValue: "*"},
+				{Token: lexer.Number, TokenValue: "5"},
+			},
+			want:    ptrInt(26),
+			wantErr: false,
+		},
+		{
+			name: "parentheses make grouping explicit",
+			elements: lexer.ElementList{
+				{Token: lexer.LParen, TokenValue: "("},
+				{Token: lexer.Number, TokenValue: "5"},
+				{Token: lexer.Minus, TokenValue: "-"},
+				{Token: lexer.Number, TokenValue: "3"},
+				{Token: lexer.RParen, TokenValue: ")"},
+				{Token: lexer.Minus, TokenValue: "-"},
+				{Token: lexer.Number, TokenValue: "1"},
+			},
+			want:    ptrInt(1),
+			wantErr: false,
+		},
+		{
+			name: "chain is rejected",
+			elements: lexer.ElementList{
+				{Token: lexer.Number, TokenValue: "5"},
+				{Token: lexer.Minus, TokenValue: "-"},
+				{Token: lexer.Number, TokenValue: "3"},
+				{Token: lexer.Plus, TokenValue: "+"},
+				{Token: lexer.Number, TokenValue: "1"},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := p.Eval(tt.elements)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Eval() err=%v wantErr=%v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !errors.Is(err, errNonAssociative) {
+					t.Fatalf("Eval() err=%v, want errNonAssociative", err)
+				}
+				return
+			}
+			if got == nil || *got != *tt.want {
+				t.Fatalf("Eval() got=%v want=%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParser_Prefix(t *testing.T) {
+	base := newTestParser()
+	operations := make([]Operation[int], len(base.Operations))
+	copy(operations, base.Operations)
+
+	for i := range operations {
+		if operations[i].TokenId == lexer.Minus {
+			operations[i].UnaryFn = func(a int) (int, error) { return -a, nil }
+		}
+	}
+
+	// Unary minus binding more tightly than exponentiation, as in a spreadsheet, or less tightly, as in Python.
+	tight := mustNewParser(operations, []OperationGroup{
+		base.OperationGroups[0],
+		{Tokens: []lexer.TokenId{lexer.Minus}, Precedence: PrecedencePostfix + 1, Fixity: Prefix},
+		{Tokens: []lexer.TokenId{lexer.Exponent}, Precedence: PrecedenceExponent + 1, Associativity: RightAssociative},
+		base.OperationGroups[2],
+		base.OperationGroups[3],
+	})
+	loose := mustNewParser(operations, []OperationGroup{
+		base.OperationGroups[0],
+		base.OperationGroups[1],
+		{Tokens: []lexer.TokenId{lexer.Minus}, Precedence: PrecedenceImplicitMultiply, Fixity: Prefix},
+		base.OperationGroups[2],
+		base.OperationGroups[3],
+	})
+
+	elements := func(values ...string) lexer.ElementList {
+		tokens := map[string]lexer.TokenId{"-": lexer.Minus, "^": lexer.Exponent, "*": lexer.Multiply, "%": lexer.Percent}
+		var list lexer.ElementList
+		for _, v := range values {
+			tok, ok := tokens[v]
+			if !ok {
+				tok = lexer.Number
+			}
+			list = append(list, lexer.Element{Token: tok, TokenValue: v})
+		}
+		return list
+	}
+
+	tests := []struct {
+		name     string
+		parser   Parser[int]
+		elements lexer.ElementList
+		want     *int
+		wantErr  bool
+	}{
+		{name: "leading minus", parser: loose, elements: elements("-", "3", "-", "4"), want: ptrInt(-7)},
+		{name: "double minus", parser: loose, elements: elements("-", "-", "3"), want: ptrInt(3)},
+		{name: "after an infix operator", parser: loose, elements: elements("2", "*", "-", "3"), want: ptrInt(-6)},
+		{name: "after a postfix operator", parser: loose, elements: elements("500", "%", "-", "3"), want: ptrInt(2)},
+		{name: "binds less tightly than ^", parser: loose, elements: elements("-", "2", "^", "2"), want: ptrInt(-4)},
+		{name: "right operand of ^", parser: loose, elements: elements("2", "^", "-", "-", "2"), want: ptrInt(4)},
+		{name: "binds more tightly than ^", parser: tight, elements: elements("-", "2", "^", "2"), want: ptrInt(4)},
+		{name: "error - no operand", parser: loose, elements: elements("3", "*", "-"), wantErr: true},
+		{name: "error - not a prefix operator", parser: base, elements: elements("-", "3"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.parser.Eval(tt.elements)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Eval() err=%v wantErr=%v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got == nil || *got != *tt.want {
+				t.Fatalf("Eval() got=%v want=%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParser_EvalFloat(t *testing.T) {
+	operations := []Operation[float64]{
+		{Description: "Plus", TokenId: lexer.Plus, Fn: func(a, b float64) (float64, error) { return a + b, nil }},
+		{Description: "Divide", TokenId: lexer.Divide, Fn: func(a, b float64) (float64, error) { return a / b, nil }},
+	}
+	opGroups := []OperationGroup{
+		{Tokens: []lexer.TokenId{lexer.Divide}, Precedence: PrecedenceMultiplyDivide, Associativity: LeftAssociative},
+		{Tokens: []lexer.TokenId{lexer.Plus}, Precedence: PrecedencePlusMinus, Associativity: LeftAssociative},
+	}
+	p := mustNewParser(operations, opGroups)
+
+	tests := []struct {
+		name     string
+		elements lexer.ElementList
+		want     float64
+		wantErr  bool
+	}{
+		{
+			name: "division keeps the fraction",
+			elements: lexer.ElementList{
+				{Token: lexer.Number, TokenValue: "7"},
+				{Token: lexer.Divide, TokenValue: "/"},
+				{Token: lexer.Number, TokenValue: "2"},
+			},
+			want: 3.5,
+		},
+		{
+			name: "decimal and exponent literals",
+			elements: lexer.ElementList{
+				{Token: lexer.Number, TokenValue: "0.25"},
+				{Token: lexer.Plus, TokenValue: "+"},
+				{Token: lexer.Number, TokenValue: "1e2"},
+			},
+			want: 100.25,
+		},
+		{
+			name: "intermediate results keep full precision",
+			elements: lexer.ElementList{
+				{Token: lexer.LParen, TokenValue: "("},
+				{Token: lexer.Number, TokenValue: "1"},
+				{Token: lexer.Divide, TokenValue: "/"},
+				{Token: lexer.Number, TokenValue: "3"},
+				{Token: lexer.RParen, TokenValue: ")"},
+				{Token: lexer.Plus, TokenValue: "+"},
+				{Token: lexer.Number, TokenValue: "1"},
+			},
+			want: 1.0/3 + 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := p.Eval(tt.elements)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Eval() err=%v wantErr=%v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if *got != tt.want {
+				t.Fatalf("Eval() got=%v want=%v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParser_EvalIntegerRejectsDecimals(t *testing.T) {
+	p := newTestParser()
+
+	_, err := p.Eval(lexer.ElementList{{Token: lexer.Number, TokenValue: "1.5"}})
+	if !errors.Is(err, errInvalidNumber) {
+		t.Fatalf("Eval() err=%v, want errInvalidNumber", err)
+	}
+}
+
+func TestParser_EvalIntegerRejectsExponents(t *testing.T) {
+	p := newTestParser()
+
+	// The lexer reads 1e6 as a number, but it isn't written as an int would be.
+	_, err := p.Eval(lexer.ElementList{{Token: lexer.Number, TokenValue: "1e6"}})
+	if !errors.Is(err, errIntegerExponent) {
+		t.Fatalf("Eval() err=%v, want %v", err, errIntegerExponent)
+	}
+}
+
+// TestParser_DeprecatedOperationFn checks that operations written with the lexer's deprecated function
+// types can still be used.
+func TestParser_DeprecatedOperationFn(t *testing.T) {
+	var (
+		times  lexer.OperationFn      = func(a, b int) (int, error) { return a * b, nil }
+		negate lexer.UnaryOperationFn = func(a int) (int, error) { return -a, nil }
+	)
+
+	p := mustNewParser([]Operation[int]{
+		{TokenId: lexer.Multiply, Fn: times},
+		{TokenId: lexer.Minus, UnaryFn: negate},
+	}, []OperationGroup{
+		{Tokens: []lexer.TokenId{lexer.Minus}, Precedence: PrecedenceImplicitMultiply, Fixity: Prefix},
+		{Tokens: []lexer.TokenId{lexer.Multiply}, Precedence: PrecedenceMultiplyDivide, Associativity: LeftAssociative},
+	})
+
+	got, err := p.Eval(lexer.ElementList{
+		{Token: lexer.Minus, TokenValue: "-"},
+		{Token: lexer.Number, TokenValue: "2"},
+		{Token: lexer.Multiply, TokenValue: "*"},
+		{Token: lexer.Number, TokenValue: "3"},
+	})
+	if err != nil || *got != -6 {
+		t.Fatalf("Eval() = (%v, %v), want -6", got, err)
+	}
+}
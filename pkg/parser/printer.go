@@ -0,0 +1,370 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/lexer"
+)
+
+// Printer writes Nodes as source text in a canonical form, so that equivalent expressions are always written
+// the same way: each operator has one spelling, infix operators are surrounded by single spaces, and
+// parentheses are only written where the OperationGroups need them.  For example ((1+2))*3 is written
+// (1 + 2) * 3 and, since exponentiation is right associative, 2^(3^2) is written 2 ^ 3 ^ 2.  The Comments
+// of each Node are written before or after it.
+type Printer struct {
+	OperationGroups []OperationGroup
+	// Comments is the comment syntax of the Comments attached to Nodes.  See LineComment.
+	Comments lexer.Comments
+	// spellings maps each TokenId to the text written for it.
+	spellings map[lexer.TokenId]string
+}
+
+// NewPrinter returns a Printer that spells operators as they are spelled by tokens and places parentheses
+// according to opGroups, which should be those of the Parser that will read the text.  Where tokens spell a
+// TokenId in more than one way, such as * and ×, the first spelling is used.  An infix operator that has
+// no spelling, such as lexer.ImplicitMultiply, is written by juxtaposing its operands.
+func NewPrinter(tokens []lexer.Token, opGroups []OperationGroup) Printer {
+	spellings := map[lexer.TokenId]string{}
+	for _, tok := range tokens {
+		if _, ok := spellings[tok.Id]; !ok {
+			spellings[tok.Id] = tok.Value
+		}
+	}
+
+	defaults := map[lexer.TokenId]string{
+		lexer.LParen: "(", lexer.RParen: ")", lexer.Comma: ",", lexer.Assign: "=", lexer.Semicolon: ";",
+	}
+
+	for tok, value := range defaults {
+		if _, ok := spellings[tok]; !ok {
+			spellings[tok] = value
+		}
+	}
+
+	return Printer{OperationGroups: opGroups, spellings: spellings}
+}
+
+// Spelling returns the text written for tok, or "" if it has none.  Punctuation such as lexer.LParen and
+// lexer.Assign is spelled as in the default language unless the Printer's tokens spell it differently.
+func (pr Printer) Spelling(tok lexer.TokenId) string {
+	return pr.spellings[tok]
+}
+
+// LineComment reports whether the comment text is a line comment, after which anything else must start on
+// a new line, rather than a block comment, which starts with Comments.BlockStart.
+func (pr Printer) LineComment(text string) bool {
+	return pr.Comments.BlockStart == "" || !strings.HasPrefix(text, pr.Comments.BlockStart)
+}
+
+// Format returns the canonical source text of n.
+func (pr Printer) Format(n Node) (string, error) {
+	w := writer{Printer: pr}
+	if err := w.write(n, len(pr.OperationGroups)); err != nil {
+		return "", err
+	}
+
+	return w.String(), nil
+}
+
+// Elements returns the elements of the canonical source text of n, as the lexer would produce them from the
+// text returned by Format with lexer.WithTrivia, except that juxtaposed operands are separated by an element
+// for their operator.  Evaluating the result with a Parser that has the Printer's OperationGroups evaluates n.
+func (pr Printer) Elements(n Node) (lexer.ElementList, error) {
+	w := writer{Printer: pr}
+	if err := w.write(n, len(pr.OperationGroups)); err != nil {
+		return nil, err
+	}
+
+	return w.elements, nil
+}
+
+// writer accumulates the elements of a Node.  infix records which elements are infix operators, which are
+// written with a space either side.
+type writer struct {
+	Printer
+	elements lexer.ElementList
+	infix    []bool
+}
+
+func (w *writer) add(tok lexer.TokenId, value string, infix bool) {
+	w.elements = append(w.elements, lexer.Element{Token: tok, TokenValue: value})
+	w.infix = append(w.infix, infix)
+}
+
+// write appends the elements of n and its Comments.  bound is the index of the loosest OperationGroup that
+// may be applied to the operand of a PrefixNode without parentheses: a prefix operator on the right of an
+// infix operator, as in 2^-3, is applied as soon as the infix operator is, so only its group's index, or
+// len(OperationGroups).
+func (w *writer) write(n Node, bound int) error {
+	w.comments(n, true)
+
+	if err := w.writeNode(n, bound); err != nil {
+		return err
+	}
+
+	w.comments(n, false)
+
+	return nil
+}
+
+// comments appends the Leading Comments of n, or the others.
+func (w *writer) comments(n Node, leading bool) {
+	for _, c := range n.Comments {
+		if c.Leading == leading {
+			w.add(lexer.Comment, c.Text, false)
+		}
+	}
+}
+
+// writeNode appends the elements of n without its Comments.
+func (w *writer) writeNode(n Node, bound int) error {
+	if err := checkOperands(n); err != nil {
+		return err
+	}
+
+	switch n.Type {
+	case NumberNode:
+		w.add(lexer.Number, n.Value, false)
+	case IdentifierNode:
+		w.add(lexer.Identifier, n.Value, false)
+	case CallNode:
+		w.add(lexer.Identifier, n.Value, false)
+		w.add(lexer.LParen, w.spellings[lexer.LParen], false)
+
+		for i, arg := range n.Operands {
+			if i > 0 {
+				w.add(lexer.Comma, w.spellings[lexer.Comma], false)
+			}
+
+			if err := w.write(arg, len(w.OperationGroups)); err != nil {
+				return err
+			}
+		}
+
+		w.add(lexer.RParen, w.spellings[lexer.RParen], false)
+	case InfixNode:
+		return w.writeInfix(n)
+	case PrefixNode:
+		g, spelling, err := w.operator(n)
+		if err != nil {
+			return err
+		}
+
+		w.add(n.Token, spelling, false)
+
+		return w.operand(n.Operands[0], min(g, bound), w.loosest(n.Operands[0]) > min(g, bound))
+	case PostfixNode:
+		g, spelling, err := w.operator(n)
+		if err != nil {
+			return err
+		}
+
+		if err := w.operand(n.Operands[0], len(w.OperationGroups), w.looser(n.Operands[0], g, LeftAssociative)); err != nil {
+			return err
+		}
+
+		w.add(n.Token, spelling, false)
+	}
+
+	return nil
+}
+
+// writeInfix appends the elements of the InfixNode n.
+func (w *writer) writeInfix(n Node) error {
+	g, spelling, err := w.operator(n)
+	if err != nil {
+		return err
+	}
+
+	lhs, rhs := n.Operands[0], n.Operands[1]
+
+	start := len(w.elements)
+	if err := w.operand(lhs, len(w.OperationGroups), w.looser(lhs, g, LeftAssociative)); err != nil {
+		return err
+	}
+
+	// Juxtaposed operands are only read as an implicit multiplication if the left one ends with a Number,
+	// Identifier or RParen, and the right one starts with an Identifier or LParen.
+	if spelling == "" && !endsOperand(w.token(len(w.elements)-1, -1)) {
+		w.truncate(start)
+
+		if err := w.operand(lhs, len(w.OperationGroups), true); err != nil {
+			return err
+		}
+	}
+
+	w.add(n.Token, spelling, true)
+
+	// A prefix operator never needs parentheses on the right of an infix operator, but its operand may.
+	parens := rhs.Type != PrefixNode && w.looser(rhs, g, RightAssociative)
+
+	start = len(w.elements)
+	if err := w.operand(rhs, g, parens); err != nil {
+		return err
+	}
+
+	if spelling == "" && !startsOperand(w.token(start, 1)) {
+		w.truncate(start)
+
+		return w.operand(rhs, g, true)
+	}
+
+	return nil
+}
+
+// operand appends the elements of the operand n, in parentheses if parens is true.  The Comments of n are
+// written outside the parentheses.
+func (w *writer) operand(n Node, bound int, parens bool) error {
+	if !parens {
+		return w.write(n, bound)
+	}
+
+	w.comments(n, true)
+	w.add(lexer.LParen, w.spellings[lexer.LParen], false)
+
+	if err := w.writeNode(n, len(w.OperationGroups)); err != nil {
+		return err
+	}
+
+	w.add(lexer.RParen, w.spellings[lexer.RParen], false)
+	w.comments(n, false)
+
+	return nil
+}
+
+// token returns the TokenId of the element at index i, or of the nearest one in direction step from there
+// that isn't a comment.
+func (w *writer) token(i, step int) lexer.TokenId {
+	for ; w.elements[i].Token == lexer.Comment; i += step {
+	}
+
+	return w.elements[i].Token
+}
+
+func (w *writer) truncate(n int) {
+	w.elements, w.infix = w.elements[:n], w.infix[:n]
+}
+
+// operator returns the index of the OperationGroup of the operator Node n, and the operator's spelling.
+func (w *writer) operator(n Node) (int, string, error) {
+	g := groupIndex(w.OperationGroups, n.Token, n.Type.fixity())
+	if g < 0 {
+		return 0, "", fmt.Errorf("%w: TokenId %d isn't in a %s OperationGroup", errInvalidOperation, n.Token, n.Type)
+	}
+
+	spelling := w.spellings[n.Token]
+	if spelling == "" && n.Type != InfixNode {
+		return 0, "", fmt.Errorf("%w: TokenId %d", errNoSpelling, n.Token)
+	}
+
+	return g, spelling, nil
+}
+
+// loosest returns the index of the OperationGroup of the operator Node n, or -1 if n isn't an operator.
+func (w *writer) loosest(n Node) int {
+	switch n.Type {
+	case InfixNode, PrefixNode, PostfixNode:
+		return groupIndex(w.OperationGroups, n.Token, n.Type.fixity())
+	default:
+		return -1
+	}
+}
+
+// looser reports whether the operand n must be in parentheses to be an operand of an operator from group g,
+// on the side where an operator from the same group is grouped with it if g has the given Associativity.
+func (w *writer) looser(n Node, g int, side Associativity) bool {
+	switch l := w.loosest(n); {
+	case l < g:
+		return false
+	case l > g:
+		return true
+	default:
+		return w.OperationGroups[g].Associativity != side
+	}
+}
+
+// checkOperands returns an error if n doesn't have the number of Operands that its Type needs.
+func checkOperands(n Node) error {
+	want := 0
+
+	switch n.Type {
+	case NumberNode, IdentifierNode:
+	case InfixNode:
+		want = 2
+	case PrefixNode, PostfixNode:
+		want = 1
+	case CallNode:
+		return nil
+	default:
+		return fmt.Errorf("%w: unknown %v", errInvalidExpression, n.Type)
+	}
+
+	if len(n.Operands) != want {
+		return fmt.Errorf("%w: %s node with %d operands, want %d", errInvalidExpression, n.Type, len(n.Operands), want)
+	}
+
+	return nil
+}
+
+// String returns the text of the elements written so far.
+func (w *writer) String() string {
+	var sb strings.Builder
+
+	// space separates what comes next from the text so far, unless that is empty or already ends with a space
+	// or new line.
+	space := func() {
+		if s := sb.String(); s != "" && !strings.HasSuffix(s, " ") && !strings.HasSuffix(s, "\n") {
+			sb.WriteString(" ")
+		}
+	}
+
+	for i, element := range w.elements {
+		switch {
+		case element.Token == lexer.Comment:
+			space()
+			sb.WriteString(element.TokenValue)
+
+			if i < len(w.elements)-1 {
+				if w.LineComment(element.TokenValue) {
+					sb.WriteString("\n")
+				} else {
+					sb.WriteString(" ")
+				}
+			}
+		case w.infix[i] && element.TokenValue == "":
+			// Juxtaposed operands are separated by a space unless the right one is in parentheses, as in 2(x + 1).
+			if w.elements[i+1].Token != lexer.LParen {
+				space()
+			}
+		case w.infix[i]:
+			space()
+			sb.WriteString(element.TokenValue + " ")
+		case element.Token == lexer.Comma:
+			sb.WriteString(element.TokenValue + " ")
+		default:
+			// Adjacent elements such as the prefix operators in - -3 are separated if they would be read as one.
+			if i > 0 && !w.infix[i-1] && w.elements[i-1].Token != lexer.Comma && merges(w.elements[i-1].TokenValue, element.TokenValue) {
+				space()
+			}
+
+			sb.WriteString(element.TokenValue)
+		}
+	}
+
+	return sb.String()
+}
+
+// merges reports whether the text a followed immediately by b could be read as a single token: either both
+// sides of the join are part of a word, as in "not x", or both are symbols, as in "- -3".
+func merges(a, b string) bool {
+	last, _ := utf8.DecodeLastRuneInString(a)
+	first, _ := utf8.DecodeRuneInString(b)
+
+	word := func(r rune) bool { return r == '_' || r == '.' || unicode.IsLetter(r) || unicode.IsDigit(r) }
+	symbol := func(r rune) bool { return !word(r) && !strings.ContainsRune("(),", r) }
+
+	return word(last) && word(first) || symbol(last) && symbol(first)
+}
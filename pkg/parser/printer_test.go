@@ -0,0 +1,254 @@
+package parser
+
+import (
+	"errors"
+	"math/rand/v2"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/lexer"
+)
+
+var printerTokens = []lexer.Token{
+	{Id: lexer.Plus, Value: "+"},
+	{Id: lexer.Minus, Value: "-"},
+	{Id: lexer.Multiply, Value: "*"},
+	{Id: lexer.Multiply, Value: "×"},
+	{Id: lexer.Divide, Value: "/"},
+	{Id: lexer.Exponent, Value: "^"},
+	{Id: lexer.Factorial, Value: "!"},
+	{Id: lexer.Percent, Value: "%"},
+	{Id: lexer.LParen, Value: "("},
+	{Id: lexer.RParen, Value: ")"},
+	{Id: lexer.Comma, Value: ","},
+}
+
+// printerParsers returns Parsers with unary minus binding more tightly than exponentiation (tight), less
+// tightly (loose), and with implicit multiplication and built-in functions (implicit).
+func printerParsers() (tight, loose, implicit Parser[int]) {
+	base := newTestParser()
+	operations := append([]Operation[int]{}, base.Operations...)
+
+	for i := range operations {
+		if operations[i].TokenId == lexer.Minus {
+			operations[i].UnaryFn = func(a int) (int, error) { return -a, nil }
+		}
+	}
+
+	operations = append(operations, Operation[int]{Description: "ImplicitMultiply", TokenId: lexer.ImplicitMultiply, Fn: operations[2].Fn})
+	minus := OperationGroup{Tokens: []lexer.TokenId{lexer.Minus}, Fixity: Prefix}
+	g := base.OperationGroups
+
+	// inOrder sets each group's Precedence to its index.
+	inOrder := func(groups ...OperationGroup) []OperationGroup {
+		for i := range groups {
+			groups[i].Precedence = Precedence(i)
+		}
+
+		return groups
+	}
+
+	tight = mustNewParser(operations, inOrder(g[0], minus, g[1], g[2], g[3]))
+	loose = mustNewParser(operations, inOrder(g[0], g[1], minus, g[2], g[3]))
+	implicit = mustNewParser(operations,
+		inOrder(g[0], g[1], OperationGroup{Tokens: []lexer.TokenId{lexer.ImplicitMultiply}}, minus, g[2], g[3]),
+		WithImplicitMultiplication(lexer.ImplicitMultiply),
+		WithBuiltins([]BuiltinFunction[int]{{Name: "max", MinArgs: 1, MaxArgs: -1, Fn: func(args ...int) (int, error) {
+			return max(args[0], args[len(args)-1]), nil
+		}}}),
+	)
+
+	return tight, loose, implicit
+}
+
+func lex(t *testing.T, s string) lexer.ElementList {
+	t.Helper()
+
+	e, err := lexer.NewLexer(s, printerTokens, lexer.WithIdentifiers(), lexer.WithSuperscripts(lexer.Exponent),
+		lexer.WithComments(lexer.DefaultComments), lexer.WithTrivia(), lexer.WithSpans()).GetElementList()
+	if err != nil {
+		t.Fatalf("GetElementList(%q) err=%v", s, err)
+	}
+
+	return e
+}
+
+func TestPrinter_Format(t *testing.T) {
+	tight, loose, implicit := printerParsers()
+
+	tests := []struct {
+		name   string
+		parser Parser[int]
+		input  string
+		want   string
+	}{
+		{name: "redundant parentheses", parser: loose, input: "((1+2))*3", want: "(1 + 2) * 3"},
+		{name: "right associative", parser: loose, input: "2^(3^2)", want: "2 ^ 3 ^ 2"},
+		{name: "right associative kept", parser: loose, input: "(2^3)^2", want: "(2 ^ 3) ^ 2"},
+		{name: "left associative", parser: loose, input: "(1-2)-3", want: "1 - 2 - 3"},
+		{name: "left associative kept", parser: loose, input: "1-(2-3)", want: "1 - (2 - 3)"},
+		{name: "precedence", parser: loose, input: "1+(2*3)", want: "1 + 2 * 3"},
+		{name: "spelling", parser: loose, input: "2×3", want: "2 * 3"},
+		{name: "superscript", parser: loose, input: "x²", want: "x ^ 2"},
+		{name: "postfix", parser: loose, input: "(3!)!+(50)%", want: "3! ! + 50%"},
+		{name: "postfix of sum", parser: loose, input: "(1+2)!", want: "(1 + 2)!"},
+		{name: "prefix", parser: loose, input: "-(-(3))", want: "- -3"},
+		{name: "prefix looser than ^", parser: loose, input: "-(2^2)", want: "-2 ^ 2"},
+		{name: "prefix looser than ^ kept", parser: loose, input: "(-2)^2", want: "(-2) ^ 2"},
+		{name: "prefix tighter than ^", parser: tight, input: "(-2)^2", want: "-2 ^ 2"},
+		{name: "prefix tighter than ^ kept", parser: tight, input: "-(2^2)", want: "-(2 ^ 2)"},
+		{name: "prefix on the right", parser: loose, input: "2^(-(1))", want: "2 ^ -1"},
+		{name: "prefix of a product on the right", parser: loose, input: "2^-(3*4)", want: "2 ^ -(3 * 4)"},
+		{name: "prefix of a power on the right", parser: loose, input: "2 * (-(3^2))", want: "2 * -3 ^ 2"},
+		{name: "call", parser: implicit, input: "max((1),2+(3))*x", want: "max(1, 2 + 3) * x"},
+		{name: "implicit multiplication", parser: implicit, input: "2 x + 3(x+1)", want: "2 x + 3(x + 1)"},
+		{name: "implicit multiplication of a number", parser: implicit, input: "x(2)", want: "x(2)"},
+		{name: "implicit multiplication of a prefix", parser: implicit, input: "x(-2)", want: "x(-2)"},
+		{name: "implicit multiplication of a postfix", parser: implicit, input: "(3!)x", want: "(3!) x"},
+		{name: "implicit multiplication of a call", parser: implicit, input: "2max(1)", want: "2 max(1)"},
+		{name: "comment", parser: loose, input: "1+2 # sum", want: "1 + 2 # sum"},
+		{name: "leading comment", parser: loose, input: "# sum\n1+2", want: "# sum\n1 + 2"},
+		{name: "block comment", parser: loose, input: "(1 /* one */ +2)*3", want: "(1 /* one */ + 2) * 3"},
+		{name: "comment before an operator", parser: loose, input: "(1+2) # sum\n*3", want: "(1 + 2) # sum\n* 3"},
+		{name: "comment in parentheses", parser: loose, input: "(1+2 # sum\n)*3", want: "(1 + 2) # sum\n* 3"},
+		{name: "comment in implicit multiplication", parser: implicit, input: "2 /* two */ x", want: "2 /* two */ x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := tt.parser.Parse(lex(t, tt.input))
+			if err != nil {
+				t.Fatalf("Parse() err=%v", err)
+			}
+
+			pr := NewPrinter(printerTokens, tt.parser.OperationGroups)
+			pr.Comments = lexer.DefaultComments
+
+			got, err := pr.Format(n)
+			if err != nil {
+				t.Fatalf("Format() err=%v", err)
+			}
+
+			if got != tt.want {
+				t.Fatalf("Format() got=%q want=%q", got, tt.want)
+			}
+
+			// The canonical form means the same as the input.
+			want, err := tt.parser.Eval(lex(t, tt.input))
+			if err != nil {
+				return
+			}
+
+			elements, err := pr.Elements(n)
+			if err != nil {
+				t.Fatalf("Elements() err=%v", err)
+			}
+
+			for _, e := range []lexer.ElementList{lex(t, got), elements} {
+				if v, err := tt.parser.Eval(e); err != nil || *v != *want {
+					t.Fatalf("Eval(%v) = (%v, %v), want %d", e, v, err, *want)
+				}
+			}
+		})
+	}
+}
+
+func TestPrinter_FormatErrors(t *testing.T) {
+	_, loose, _ := printerParsers()
+	pr := NewPrinter([]lexer.Token{{Id: lexer.Plus, Value: "+"}}, loose.OperationGroups)
+	one := Node{Type: NumberNode, Value: "1"}
+
+	tests := []struct {
+		name string
+		node Node
+		want error
+	}{
+		{name: "operator not in a group", node: Node{Type: PrefixNode, Token: lexer.Plus, Operands: []Node{one}}, want: errInvalidOperation},
+		{name: "no spelling", node: Node{Type: PrefixNode, Token: lexer.Minus, Operands: []Node{one}}, want: errNoSpelling},
+		{name: "missing operand", node: Node{Type: InfixNode, Token: lexer.Plus, Operands: []Node{one}}, want: errInvalidExpression},
+		{name: "unknown type", node: Node{Type: 99}, want: errInvalidExpression},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := pr.Format(tt.node); !errors.Is(err, tt.want) {
+				t.Fatalf("Format() err=%v want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// TestPrinter_RoundTrip checks that random trees are parsed back from their canonical form unchanged, so
+// the Printer never leaves out parentheses that are needed.
+func TestPrinter_RoundTrip(t *testing.T) {
+	tight, loose, implicit := printerParsers()
+	r := rand.New(rand.NewPCG(1, 2))
+
+	for _, p := range []Parser[int]{tight, loose, implicit} {
+		pr := NewPrinter(printerTokens, p.OperationGroups)
+
+		for range 2000 {
+			want := randomNode(r, p, 4)
+
+			text, err := pr.Format(want)
+			if err != nil {
+				t.Fatalf("Format(%v) err=%v", want, err)
+			}
+
+			got, err := p.Parse(lex(t, text))
+			if err != nil {
+				t.Fatalf("Parse(%q) err=%v", text, err)
+			}
+
+			if !reflect.DeepEqual(withoutSpans(got), want) {
+				t.Fatalf("Parse(Format()) of %q got=%v want=%v", text, withoutSpans(got), want)
+			}
+		}
+	}
+}
+
+// randomNode returns a random expression using the operators of p, nested at most depth deep.
+func randomNode(r *rand.Rand, p Parser[int], depth int) Node {
+	if depth == 0 || r.IntN(4) == 0 {
+		if r.IntN(2) == 0 {
+			return Node{Type: NumberNode, Value: strconv.Itoa(r.IntN(10))}
+		}
+
+		return Node{Type: IdentifierNode, Value: "x"}
+	}
+
+	group := p.OperationGroups[r.IntN(len(p.OperationGroups))]
+	tok := group.Tokens[r.IntN(len(group.Tokens))]
+
+	value := ""
+	for _, token := range printerTokens {
+		if token.Id == tok {
+			value = token.Value
+			break
+		}
+	}
+
+	n := Node{Token: tok, Value: value, Operands: []Node{randomNode(r, p, depth-1)}}
+
+	switch group.Fixity {
+	case Infix:
+		n.Type = InfixNode
+		n.Operands = append(n.Operands, randomNode(r, p, depth-1))
+	case Prefix:
+		n.Type = PrefixNode
+	case Postfix:
+		n.Type = PostfixNode
+	}
+
+	return n
+}
+
+func withoutSpans(n Node) Node {
+	n.Span = lexer.Span{}
+	for i := range n.Operands {
+		n.Operands[i] = withoutSpans(n.Operands[i])
+	}
+
+	return n
+}
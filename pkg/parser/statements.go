@@ -0,0 +1,219 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/lexer"
+)
+
+// Statement is an expression, an assignment of an expression's value to the variable Name, or, if Params
+// isn't nil, the definition of a Function called Name with Expression as its body.
+type Statement struct {
+	Name       string
+	Params     []string
+	Expression lexer.ElementList
+}
+
+// SplitStatements divides a script into Statements at each Semicolon element, skipping empty statements.
+// A statement that starts with an Identifier followed by an Assign element is an assignment, and one that
+// starts with an Identifier and a parenthesised list of parameters followed by an Assign is a definition.
+// Comment elements before the Assign are moved to the start of the Expression, and those of a statement with
+// nothing else are added to the Expression of the statement before, or of the first statement.
+func SplitStatements(e lexer.ElementList) ([]Statement, error) {
+	var statements []Statement
+
+	// comments holds the Comment elements found before the first statement.
+	var comments lexer.ElementList
+
+	for _, part := range splitAt(e, lexer.Semicolon) {
+		if !slices.ContainsFunc(part, isCode) {
+			if n := len(statements); n > 0 {
+				statements[n-1].Expression = slices.Concat(statements[n-1].Expression, part)
+			} else {
+				comments = append(comments, part...)
+			}
+
+			continue
+		}
+
+		var s Statement
+
+		idx := slices.IndexFunc(part, func(el lexer.Element) bool { return el.Token == lexer.Assign })
+		head := part[:max(idx, 0)]
+
+		for _, el := range head {
+			if !isCode(el) {
+				comments = append(comments, el)
+			}
+		}
+
+		head = slices.DeleteFunc(slices.Clone(head), func(el lexer.Element) bool { return !isCode(el) })
+
+		switch n := len(head); {
+		case idx < 0:
+		case n == 1 && head[0].Token == lexer.Identifier:
+			s.Name = head[0].TokenValue
+		case n > 2 && head[0].Token == lexer.Identifier && head[1].Token == lexer.LParen && head[n-1].Token == lexer.RParen:
+			params, err := parseParams(head[2 : n-1])
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s: %w", errInvalidDefinition, head[0].TokenValue, err)
+			}
+
+			s.Name, s.Params = head[0].TokenValue, params
+		case n == 1:
+			return nil, fmt.Errorf("%w to %q", errInvalidAssignment, head[0].TokenValue)
+		default:
+			return nil, fmt.Errorf("%w to %v", errInvalidAssignment, head)
+		}
+
+		s.Expression = part[idx+1:]
+		if !slices.ContainsFunc(s.Expression, isCode) {
+			return nil, fmt.Errorf("%w: nothing after = in %v", errInvalidAssignment, part)
+		}
+
+		if slices.ContainsFunc(s.Expression, func(el lexer.Element) bool { return el.Token == lexer.Assign }) {
+			return nil, fmt.Errorf("%w: more than one = in %v", errInvalidAssignment, part)
+		}
+
+		if comments != nil {
+			s.Expression, comments = slices.Concat(comments, s.Expression), nil
+		}
+
+		statements = append(statements, s)
+	}
+
+	if comments != nil {
+		// There are only comments, which Parse rejects like any other empty expression.
+		statements = append(statements, Statement{Expression: comments})
+	}
+
+	return statements, nil
+}
+
+// isCode reports whether el is anything other than a Comment.
+func isCode(el lexer.Element) bool {
+	return el.Token != lexer.Comment
+}
+
+// parseParams returns the names in a list of parameters such as x, y.  The result isn't nil, even if
+// there are no parameters.
+func parseParams(e lexer.ElementList) ([]string, error) {
+	params := []string{}
+	if len(e) == 0 {
+		return params, nil
+	}
+
+	for i, part := range splitAt(e, lexer.Comma) {
+		if len(part) != 1 || part[0].Token != lexer.Identifier {
+			return nil, fmt.Errorf("parameter %d: expected a name, got %v", i+1, part)
+		}
+
+		if slices.Contains(params, part[0].TokenValue) {
+			return nil, fmt.Errorf("parameter %s is repeated", part[0].TokenValue)
+		}
+
+		params = append(params, part[0].TokenValue)
+	}
+
+	return params, nil
+}
+
+// splitAt splits e into the runs of elements between elements with TokenId tok.
+func splitAt(e lexer.ElementList, tok lexer.TokenId) []lexer.ElementList {
+	var parts []lexer.ElementList
+
+	start := 0
+
+	for i, element := range e {
+		if element.Token == tok {
+			parts = append(parts, e[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(parts, e[start:])
+}
+
+// EvalStatements evaluates a script such as rate = 0.07; net = 120; net * (1 + rate).  The statements are
+// evaluated in order, so each one can use the variables assigned and Functions defined by those before it,
+// and the value of the last statement that isn't a definition is returned, or nil if they are all definitions.
+// Assignments and definitions are made in the Parser's Environment, or in an Environment that only lasts for
+// the script if there isn't one.
+func (p Parser[T]) EvalStatements(e lexer.ElementList) (*T, error) {
+	return p.EvalStatementsContext(context.Background(), e)
+}
+
+// EvalStatementsContext is EvalStatements with a Context, which stops the evaluation with ctx.Err() if it is
+// cancelled.  The Parser's Limits apply to the script as a whole.
+func (p Parser[T]) EvalStatementsContext(ctx context.Context, e lexer.ElementList) (*T, error) {
+	p, err := p.start(ctx, e)
+	if err != nil {
+		return nil, err
+	}
+
+	statements, err := SplitStatements(e)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(statements) == 0 {
+		return nil, fmt.Errorf("%w: no statements", errInvalidExpression)
+	}
+
+	env, _ := p.environment.(*Environment[T])
+	if env == nil {
+		env = NewEnvironment[T]()
+		p.environment = env
+	}
+
+	var result *T
+
+	for _, s := range statements {
+		if err := p.checkConstants(s); err != nil {
+			return nil, err
+		}
+
+		if s.Params != nil {
+			env.Define(s.Name, Function{Params: s.Params, Body: slices.Clone(s.Expression)})
+			continue
+		}
+
+		result, err = p.eval(s.Expression)
+		if err != nil {
+			return nil, err
+		}
+
+		if s.Name != "" {
+			env.Set(s.Name, *result)
+		}
+	}
+
+	return result, nil
+}
+
+// checkConstants returns an error if s assigns to a constant, defines a Function with a constant's name, or
+// uses a constant as a parameter.  A Function named pi would otherwise make pi(2) call it while pi alone was
+// still the constant.
+func (p Parser[T]) checkConstants(s Statement) error {
+	if _, ok := p.constant(s.Name); ok {
+		if s.Params == nil {
+			return fmt.Errorf("%w to constant %q", errInvalidAssignment, s.Name)
+		}
+
+		return fmt.Errorf("%w: %s is a constant", errInvalidDefinition, s.Name)
+	}
+
+	if s.Params == nil {
+		return nil
+	}
+
+	for _, param := range s.Params {
+		if _, ok := p.constant(param); ok {
+			return fmt.Errorf("%w: %s: parameter %s is a constant", errInvalidDefinition, s.Name, param)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package parser
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/lexer"
+)
+
+func TestParser_EvalStatements(t *testing.T) {
+	p := newTestParser()
+
+	num := func(v string) lexer.Element { return lexer.Element{Token: lexer.Number, TokenValue: v} }
+	id := func(v string) lexer.Element { return lexer.Element{Token: lexer.Identifier, TokenValue: v} }
+	assign := lexer.Element{Token: lexer.Assign, TokenValue: "="}
+	semi := lexer.Element{Token: lexer.Semicolon, TokenValue: ";"}
+	plus := lexer.Element{Token: lexer.Plus, TokenValue: "+"}
+	times := lexer.Element{Token: lexer.Multiply, TokenValue: "*"}
+	comment := func(v string) lexer.Element { return lexer.Element{Token: lexer.Comment, TokenValue: v} }
+
+	tests := []struct {
+		name     string
+		elements lexer.ElementList
+		want     *int
+		wantErr  error
+	}{
+		{
+			name:     "single expression",
+			elements: lexer.ElementList{num("1"), plus, num("2")},
+			want:     ptrInt(3),
+		},
+		{
+			// a = 2; b = a * 3; a + b
+			name:     "later statements see earlier assignments",
+			elements: lexer.ElementList{id("a"), assign, num("2"), semi, id("b"), assign, id("a"), times, num("3"), semi, id("a"), plus, id("b")},
+			want:     ptrInt(8),
+		},
+		{
+			// a = 2; a = a + 1;
+			name:     "reassignment and trailing semicolon",
+			elements: lexer.ElementList{id("a"), assign, num("2"), semi, id("a"), assign, id("a"), plus, num("1"), semi},
+			want:     ptrInt(3),
+		},
+		{
+			// b = a; a = 1
+			name:     "variables are not visible before they are assigned",
+			elements: lexer.ElementList{id("b"), assign, id("a"), semi, id("a"), assign, num("1")},
+			wantErr:  errUndefinedIdentifier,
+		},
+		{
+			name:     "assignment to a number",
+			elements: lexer.ElementList{num("2"), assign, num("3")},
+			wantErr:  errInvalidAssignment,
+		},
+		{
+			name:     "assignment to an expression",
+			elements: lexer.ElementList{id("a"), plus, id("b"), assign, num("3")},
+			wantErr:  errInvalidAssignment,
+		},
+		{
+			// # a\n a /* two */ = 2; # b\n; a
+			name:     "comments",
+			elements: lexer.ElementList{comment("# a"), id("a"), comment("/* two */"), assign, num("2"), semi, comment("# b"), semi, id("a")},
+			want:     ptrInt(2),
+		},
+		{
+			name:     "no statements",
+			elements: lexer.ElementList{semi, semi},
+			wantErr:  errInvalidExpression,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := p.EvalStatements(tt.elements)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("EvalStatements() err=%v wantErr=%v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if got == nil || *got != *tt.want {
+				t.Fatalf("EvalStatements() got=%v want=%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParser_EvalStatementsUsesEnvironment(t *testing.T) {
+	env := NewEnvironment[int]()
+	base := newTestParser()
+	p := mustNewParser(base.Operations, base.OperationGroups, WithEnvironment(env))
+
+	elements := lexer.ElementList{
+		{Token: lexer.Identifier, TokenValue: "x"},
+		{Token: lexer.Assign, TokenValue: "="},
+		{Token: lexer.Number, TokenValue: "5"},
+	}
+
+	if _, err := p.EvalStatements(elements); err != nil {
+		t.Fatalf("EvalStatements() err=%v", err)
+	}
+
+	if x, ok := env.Get("x"); !ok || x != 5 {
+		t.Fatalf("x = %v, %v; want 5", x, ok)
+	}
+}
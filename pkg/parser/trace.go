@@ -0,0 +1,74 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/lexer"
+)
+
+// Trace records the reductions made while evaluating, in the order they were made.  See WithTrace.
+type Trace[T Number] struct {
+	Steps []Step[T]
+}
+
+// Step is a single reduction, such as 3*4 = 12.  Description is the Description of the Operation or
+// the name of the function called, and Expression is what was reduced, written with its operands'
+// values, e.g. "3*4" or "f(3, 4)".  Span locates Expression's source text, which may include
+// parentheses and names rather than values, if the lexer recorded spans.
+type Step[T Number] struct {
+	Description string
+	Expression  string
+	Operands    []T
+	Result      T
+	Span        lexer.Span
+}
+
+func (s Step[T]) String() string {
+	return s.Expression + " = " + formatNumber(s.Result)
+}
+
+// WithTrace appends a Step to trace for each Operation applied and function called.  The Number type
+// of trace must match the Number type of the Parser.  Steps made before an error are kept.
+func WithTrace[T Number](trace *Trace[T]) Option {
+	return func(o *options) {
+		o.trace = trace
+	}
+}
+
+// record appends a Step to the Parser's Trace, if it has one.
+func (p Parser[T]) record(step Step[T]) {
+	if trace, _ := p.trace.(*Trace[T]); trace != nil {
+		trace.Steps = append(trace.Steps, step)
+	}
+}
+
+// spanOf returns the Span covering every element of e.
+func spanOf(e lexer.ElementList) lexer.Span {
+	var span lexer.Span
+	for _, element := range e {
+		span = span.Join(element.Span)
+	}
+
+	return span
+}
+
+// infixExpression returns the text of the [number, operator, number] elements in e, e.g. "3*4".  An operator
+// with no spelling, such as one inserted for implicit multiplication, is written as a space.
+func infixExpression(e lexer.ElementList) string {
+	op := e[1].TokenValue
+	if op == "" {
+		op = " "
+	}
+
+	return e[0].TokenValue + op + e[2].TokenValue
+}
+
+// callExpression returns the text of a call to the named function with args, e.g. "f(3, 4)".
+func callExpression[T Number](name string, args []T) string {
+	values := make([]string, len(args))
+	for i, arg := range args {
+		values[i] = formatNumber(arg)
+	}
+
+	return name + "(" + strings.Join(values, ", ") + ")"
+}
@@ -0,0 +1,90 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/lexer"
+)
+
+func TestParser_Trace(t *testing.T) {
+	tokens := []lexer.Token{
+		{Id: lexer.Plus, Value: "+"},
+		{Id: lexer.Multiply, Value: "*"},
+		{Id: lexer.Divide, Value: "/"},
+		{Id: lexer.Factorial, Value: "!"},
+		{Id: lexer.LParen, Value: "("},
+		{Id: lexer.RParen, Value: ")"},
+		{Id: lexer.Assign, Value: "="},
+		{Id: lexer.Semicolon, Value: ";"},
+		{Id: lexer.Comma, Value: ","},
+	}
+
+	tests := []struct {
+		name      string
+		input     string
+		wantSteps []string
+		wantSpans []lexer.Span
+		wantErr   bool
+	}{
+		{
+			name:      "precedence",
+			input:     "2 + 3*4",
+			wantSteps: []string{"3*4 = 12", "2+12 = 14"},
+			wantSpans: []lexer.Span{{Start: 4, End: 7}, {Start: 0, End: 7}},
+		},
+		{
+			name:      "parentheses",
+			input:     "(1+2)*3!",
+			wantSteps: []string{"1+2 = 3", "3! = 6", "3*6 = 18"},
+			wantSpans: []lexer.Span{{Start: 1, End: 4}, {Start: 6, End: 8}, {Start: 0, End: 8}},
+		},
+		{
+			name:      "function call",
+			input:     "f(x) = x*x; 1 + f(2+1)",
+			wantSteps: []string{"2+1 = 3", "3*3 = 9", "f(3) = 9", "1+9 = 10"},
+			wantSpans: []lexer.Span{{Start: 18, End: 21}, {Start: 7, End: 10}, {Start: 16, End: 22}, {Start: 12, End: 22}},
+		},
+		{
+			name:      "steps before an error are kept",
+			input:     "2*3 + 1/0",
+			wantSteps: []string{"2*3 = 6"},
+			wantSpans: []lexer.Span{{Start: 0, End: 3}},
+			wantErr:   true,
+		},
+	}
+
+	base := newTestParser()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			elements, err := lexer.NewLexer(tt.input, tokens, lexer.WithIdentifiers(), lexer.WithSpans()).GetElementList()
+			if err != nil {
+				t.Fatalf("GetElementList() err=%v", err)
+			}
+
+			var trace Trace[int]
+
+			p := mustNewParser(base.Operations, base.OperationGroups, WithTrace(&trace))
+
+			_, err = p.EvalStatements(elements)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("EvalStatements() err=%v wantErr=%v", err, tt.wantErr)
+			}
+
+			var (
+				steps []string
+				spans []lexer.Span
+			)
+
+			for _, step := range trace.Steps {
+				steps = append(steps, step.String())
+				spans = append(spans, step.Span)
+			}
+
+			if !reflect.DeepEqual(steps, tt.wantSteps) || !reflect.DeepEqual(spans, tt.wantSpans) {
+				t.Fatalf("Trace = %v %v, want %v %v", steps, spans, tt.wantSteps, tt.wantSpans)
+			}
+		})
+	}
+}
@@ -4,37 +4,106 @@ import (
 	"github.com/LaoZhuBaba/arithmetic_parser/pkg/lexer"
 )
 
-type precedence int8
-type associativity int8
+type Precedence int8
+type Associativity int8
+type Fixity int8
 
-// The following values define the order in which OperationGroups are evaluated.
-// Order is significant because lower numbers are evaluated before higher numbers.
+// The following values name the precedence levels of the default OperationGroups.
+// OperationGroups are evaluated in the order they appear in Parser.OperationGroups, so
+// they should be listed from the lowest value (evaluated first) to the highest.
 const (
-	PrecedenceExponent precedence = iota
+	PrecedencePostfix Precedence = iota
+	PrecedenceExponent
+	PrecedenceImplicitMultiply
 	PrecedenceMultiplyDivide
 	PrecedencePlusMinus
 )
 
-type Parser struct {
-	Operations      []Operation
+// Number is the set of types a Parser can evaluate expressions with.  Parser[int] performs
+// integer arithmetic, e.g. 7/2 is 3, and Parser[float64] performs floating point arithmetic.
+type Number interface {
+	int | float64
+}
+
+type OperationFn[T Number] func(T, T) (T, error)
+
+type UnaryOperationFn[T Number] func(T) (T, error)
+
+type Parser[T Number] struct {
+	Operations      []Operation[T]
 	OperationGroups []OperationGroup
+	options
+	// callDepth is the number of Function calls being evaluated.
+	callDepth int
+	// budget is shared by every copy of the Parser made during one evaluation, or nil outside one.
+	budget *budget
+}
+
+// options holds the settings made by Option functions.  They are kept separate from Parser
+// so that an Option doesn't depend on the Parser's Number type.
+type options struct {
+	// implicitMultiply is the TokenId inserted between juxtaposed operands, or NullToken
+	// if implicit multiplication is disabled.
+	implicitMultiply lexer.TokenId
+	// environment is an *Environment[T] matching the Parser's Number type, or nil.
+	environment any
+	// maxCallDepth limits the nesting of Function calls, or is 0 for DefaultMaxCallDepth.
+	maxCallDepth int
+	// builtins is a []BuiltinFunction[T] matching the Parser's Number type, or nil.
+	builtins any
+	// constants is a map[string]T matching the Parser's Number type, or nil.
+	constants any
+	// trace is a *Trace[T] matching the Parser's Number type, or nil.
+	trace any
+	// observer is an Observer[T] matching the Parser's Number type, or nil.
+	observer any
+	limits   Limits
 }
 
-type Operation struct {
+// Option configures optional Parser behaviour.
+type Option func(*options)
+
+// Operation maps a TokenId to the function that implements it.  Fn is used when the operation
+// belongs to an Infix OperationGroup and UnaryFn when it belongs to a Postfix or Prefix OperationGroup.
+// An Operation such as Minus may have both, and belong to both an Infix and a Prefix group.
+type Operation[T Number] struct {
 	Description string
 	TokenId     lexer.TokenId
-	Fn          lexer.OperationFn
+	Fn          OperationFn[T]
+	UnaryFn     UnaryOperationFn[T]
+}
+
+// BuiltinFunction is a function implemented in Go, such as sqrt, that expressions can call by Name.
+// It accepts between MinArgs and MaxArgs arguments, or any number from MinArgs up if MaxArgs is -1.
+type BuiltinFunction[T Number] struct {
+	Name    string
+	MinArgs int
+	MaxArgs int
+	Fn      func(args ...T) (T, error)
 }
 
+// NonAssociative operators cannot be chained: with < declared NonAssociative, a<b<c is
+// rejected rather than being grouped as (a<b)<c.
 const (
-	LeftAssociative associativity = iota
+	LeftAssociative Associativity = iota
 	RightAssociative
+	NonAssociative
+)
+
+// Infix operators take a Number on each side, e.g. 2+3.  Postfix operators follow
+// a single Number, e.g. 5!.  Prefix operators precede a single Number, e.g. -3, and are
+// applied from right to left, so the Associativity of a Prefix group is ignored.
+const (
+	Infix Fixity = iota
+	Postfix
+	Prefix
 )
 
 // OperationGroup defines a group of Operations that share the same precedence.
 // and associativity.  Each Operation is identified by a TokenId.
 type OperationGroup struct {
 	Tokens        []lexer.TokenId
-	Associativity associativity
-	Precedence    precedence
+	Associativity Associativity
+	Precedence    Precedence
+	Fixity        Fixity
 }
@@ -0,0 +1,184 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/lexer"
+)
+
+// validate checks that the Parser's Operations and OperationGroups are consistent, so that mistakes in
+// the configuration are reported by NewParser rather than as confusing errors from Eval.
+func (p Parser[T]) validate() error {
+	if err := p.validateTypes(); err != nil {
+		return err
+	}
+
+	operations := map[lexer.TokenId]Operation[T]{}
+
+	for _, op := range p.Operations {
+		if isOperand(op.TokenId) {
+			return fmt.Errorf("%w: %s uses reserved TokenId %d", errInvalidConfiguration, op.Description, op.TokenId)
+		}
+
+		if other, ok := operations[op.TokenId]; ok {
+			return fmt.Errorf("%w: %s and %s both use TokenId %d",
+				errInvalidConfiguration, other.Description, op.Description, op.TokenId)
+		}
+
+		if op.Fn == nil && op.UnaryFn == nil {
+			return fmt.Errorf("%w: %s has neither Fn nor UnaryFn", errInvalidConfiguration, op.Description)
+		}
+
+		operations[op.TokenId] = op
+	}
+
+	// A token may be in one Prefix group and one Infix or Postfix group, which are keyed by prefix == false.
+	type key struct {
+		tok    lexer.TokenId
+		prefix bool
+	}
+
+	grouped := map[key]int{}
+
+	for i, group := range p.OperationGroups {
+		// Groups are evaluated in slice order, so their Precedence values must increase with the index.
+		if i > 0 && group.Precedence <= p.OperationGroups[i-1].Precedence {
+			return fmt.Errorf("%w: OperationGroup %d has Precedence %d, which doesn't follow %d at index %d",
+				errInvalidConfiguration, i, group.Precedence, p.OperationGroups[i-1].Precedence, i-1)
+		}
+
+		if group.Associativity < LeftAssociative || group.Associativity > NonAssociative {
+			return fmt.Errorf("%w: OperationGroup %d has invalid Associativity %d", errInvalidConfiguration, i, group.Associativity)
+		}
+
+		switch group.Fixity {
+		case Infix, Prefix:
+		case Postfix:
+			if group.Associativity == RightAssociative {
+				return fmt.Errorf("%w: OperationGroup %d: postfix operators cannot be right associative", errInvalidConfiguration, i)
+			}
+		default:
+			return fmt.Errorf("%w: OperationGroup %d has invalid Fixity %d", errInvalidConfiguration, i, group.Fixity)
+		}
+
+		for _, tok := range group.Tokens {
+			op, ok := operations[tok]
+			if !ok {
+				return fmt.Errorf("%w: OperationGroup %d refers to TokenId %d, which has no Operation", errInvalidConfiguration, i, tok)
+			}
+
+			k := key{tok, group.Fixity == Prefix}
+			if prev, ok := grouped[k]; ok {
+				return fmt.Errorf("%w: %s is in OperationGroups %d and %d", errInvalidConfiguration, op.Description, prev, i)
+			}
+
+			if group.Fixity == Infix && op.Fn == nil {
+				return fmt.Errorf("%w: %s is in infix OperationGroup %d but has no Fn", errInvalidConfiguration, op.Description, i)
+			}
+
+			if group.Fixity != Infix && op.UnaryFn == nil {
+				return fmt.Errorf("%w: %s is in OperationGroup %d but has no UnaryFn", errInvalidConfiguration, op.Description, i)
+			}
+
+			grouped[k] = i
+		}
+	}
+
+	for i, group := range p.OperationGroups {
+		if group.Fixity != Prefix {
+			continue
+		}
+
+		for _, tok := range group.Tokens {
+			other, ok := grouped[key{tok, false}]
+			if !ok {
+				continue
+			}
+
+			// Both kinds of group use UnaryFn, so a token can't be prefix and postfix.
+			if p.OperationGroups[other].Fixity == Postfix {
+				return fmt.Errorf("%w: %s is both a prefix and a postfix operator", errInvalidConfiguration, operations[tok].Description)
+			}
+
+			// An infix group evaluated first would take the prefix operator in -1+2 as its own, without a left operand.
+			if other < i {
+				return fmt.Errorf("%w: %s is in prefix OperationGroup %d, which must come before infix OperationGroup %d",
+					errInvalidConfiguration, operations[tok].Description, i, other)
+			}
+		}
+	}
+
+	if p.implicitMultiply != lexer.NullToken {
+		if _, ok := grouped[key{p.implicitMultiply, false}]; !ok {
+			return fmt.Errorf("%w: implicit multiplication uses TokenId %d, which isn't in an OperationGroup",
+				errInvalidConfiguration, p.implicitMultiply)
+		}
+	}
+
+	return p.validateBuiltins()
+}
+
+// validateBuiltins checks that each BuiltinFunction has a unique Name, an Fn and a sensible range of arguments.
+func (p Parser[T]) validateBuiltins() error {
+	fns, _ := p.builtins.([]BuiltinFunction[T])
+	names := map[string]bool{}
+
+	for _, fn := range fns {
+		switch {
+		case fn.Name == "":
+			return fmt.Errorf("%w: BuiltinFunction has no Name", errInvalidConfiguration)
+		case names[fn.Name]:
+			return fmt.Errorf("%w: BuiltinFunction %s is defined twice", errInvalidConfiguration, fn.Name)
+		case fn.Fn == nil:
+			return fmt.Errorf("%w: BuiltinFunction %s has no Fn", errInvalidConfiguration, fn.Name)
+		case fn.MinArgs < 0 || fn.MaxArgs < -1 || fn.MaxArgs >= 0 && fn.MaxArgs < fn.MinArgs:
+			return fmt.Errorf("%w: BuiltinFunction %s takes %d to %d arguments", errInvalidConfiguration, fn.Name, fn.MinArgs, fn.MaxArgs)
+		}
+
+		names[fn.Name] = true
+	}
+
+	return nil
+}
+
+// validateTypes checks that the options whose Number type can't be checked by the compiler match the
+// Parser's, since one that didn't, such as WithBuiltins[float64] given to a Parser[int], would be ignored.
+func (p Parser[T]) validateTypes() error {
+	options := []struct {
+		name  string
+		value any
+		ok    bool
+	}{
+		{"WithEnvironment", p.environment, is[*Environment[T]](p.environment)},
+		{"WithBuiltins", p.builtins, is[[]BuiltinFunction[T]](p.builtins)},
+		{"WithConstants", p.constants, is[map[string]T](p.constants)},
+		{"WithTrace", p.trace, is[*Trace[T]](p.trace)},
+		{"WithObserver", p.observer, is[Observer[T]](p.observer)},
+	}
+
+	for _, o := range options {
+		if o.value != nil && !o.ok {
+			var zero T
+			return fmt.Errorf("%w: %s was given a %T, not one for %T", errInvalidConfiguration, o.name, o.value, zero)
+		}
+	}
+
+	return nil
+}
+
+// is reports whether v holds a V.
+func is[V any](v any) bool {
+	_, ok := v.(V)
+	return ok
+}
+
+// isOperand reports whether tok is one of the TokenIds the lexer uses for things other than operators.
+func isOperand(tok lexer.TokenId) bool {
+	switch tok {
+	case lexer.NullToken, lexer.Number, lexer.LParen, lexer.RParen, lexer.Identifier, lexer.Comment,
+		lexer.Assign, lexer.Semicolon:
+		return true
+	default:
+		return false
+	}
+}
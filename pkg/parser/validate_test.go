@@ -0,0 +1,187 @@
+package parser
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/LaoZhuBaba/arithmetic_parser/pkg/lexer"
+)
+
+func TestNewParser_Validation(t *testing.T) {
+	base := newTestParser()
+	add := func(a, b int) (int, error) { return a + b, nil }
+	first := func(args ...int) (int, error) { return args[0], nil }
+
+	tests := []struct {
+		name       string
+		operations []Operation[int]
+		opGroups   []OperationGroup
+		opts       []Option
+		wantErr    bool
+	}{
+		{
+			name:       "valid configuration",
+			operations: base.Operations,
+			opGroups:   base.OperationGroups,
+			wantErr:    false,
+		},
+		{
+			name:       "group token without an Operation",
+			operations: base.Operations[:2],
+			opGroups:   base.OperationGroups,
+			wantErr:    true,
+		},
+		{
+			name: "duplicate TokenId",
+			operations: []Operation[int]{
+				{Description: "Plus", TokenId: lexer.Plus, Fn: add},
+				{Description: "Also Plus", TokenId: lexer.Plus, Fn: add},
+			},
+			opGroups: []OperationGroup{{Tokens: []lexer.TokenId{lexer.Plus}}},
+			wantErr:  true,
+		},
+		{
+			name:       "reserved TokenId",
+			operations: []Operation[int]{{Description: "Paren", TokenId: lexer.LParen, Fn: add}},
+			opGroups:   nil,
+			wantErr:    true,
+		},
+		{
+			name:       "Operation without a function",
+			operations: []Operation[int]{{Description: "Plus", TokenId: lexer.Plus}},
+			opGroups:   nil,
+			wantErr:    true,
+		},
+		{
+			name:       "Precedence out of order",
+			operations: base.Operations,
+			opGroups: []OperationGroup{
+				base.OperationGroups[0],
+				base.OperationGroups[3],
+				base.OperationGroups[2],
+				base.OperationGroups[1],
+			},
+			wantErr: true,
+		},
+		{
+			name:       "token in two groups",
+			operations: base.Operations,
+			opGroups: append(base.OperationGroups[:4:4],
+				OperationGroup{Tokens: []lexer.TokenId{lexer.Plus}, Precedence: PrecedencePlusMinus + 1}),
+			wantErr: true,
+		},
+		{
+			name:       "infix Operation in a postfix group",
+			operations: base.Operations,
+			opGroups: []OperationGroup{
+				{Tokens: []lexer.TokenId{lexer.Plus}, Precedence: PrecedencePostfix, Fixity: Postfix},
+			},
+			wantErr: true,
+		},
+		{
+			name:       "right associative postfix group",
+			operations: base.Operations,
+			opGroups: []OperationGroup{
+				{Tokens: []lexer.TokenId{lexer.Factorial}, Associativity: RightAssociative, Fixity: Postfix},
+			},
+			wantErr: true,
+		},
+		{
+			name:       "prefix Operation without UnaryFn",
+			operations: base.Operations,
+			opGroups: []OperationGroup{
+				{Tokens: []lexer.TokenId{lexer.Minus}, Fixity: Prefix},
+				{Tokens: []lexer.TokenId{lexer.Minus}, Precedence: PrecedencePlusMinus},
+			},
+			wantErr: true,
+		},
+		{
+			name: "prefix group after the infix group with the same token",
+			operations: []Operation[int]{
+				{Description: "Minus", TokenId: lexer.Minus, Fn: add, UnaryFn: func(a int) (int, error) { return -a, nil }},
+			},
+			opGroups: []OperationGroup{
+				{Tokens: []lexer.TokenId{lexer.Minus}, Precedence: PrecedencePlusMinus},
+				{Tokens: []lexer.TokenId{lexer.Minus}, Precedence: PrecedencePlusMinus + 1, Fixity: Prefix},
+			},
+			wantErr: true,
+		},
+		{
+			name:       "prefix and postfix",
+			operations: base.Operations,
+			opGroups: []OperationGroup{
+				{Tokens: []lexer.TokenId{lexer.Factorial}, Fixity: Prefix},
+				{Tokens: []lexer.TokenId{lexer.Factorial}, Precedence: PrecedencePostfix + 1, Fixity: Postfix},
+			},
+			wantErr: true,
+		},
+		{
+			name:       "implicit multiplication without a group",
+			operations: base.Operations,
+			opGroups:   base.OperationGroups,
+			opts:       []Option{WithImplicitMultiplication(lexer.ImplicitMultiply)},
+			wantErr:    true,
+		},
+		{
+			name:       "BuiltinFunction defined twice",
+			operations: base.Operations,
+			opGroups:   base.OperationGroups,
+			opts:       []Option{WithBuiltins([]BuiltinFunction[int]{{Name: "f", Fn: first}, {Name: "f", Fn: first}})},
+			wantErr:    true,
+		},
+		{
+			name:       "BuiltinFunction with MaxArgs below MinArgs",
+			operations: base.Operations,
+			opGroups:   base.OperationGroups,
+			opts:       []Option{WithBuiltins([]BuiltinFunction[int]{{Name: "f", MinArgs: 2, MaxArgs: 1, Fn: first}})},
+			wantErr:    true,
+		},
+		{
+			name:       "no BuiltinFunctions",
+			operations: base.Operations,
+			opGroups:   base.OperationGroups,
+			opts:       []Option{WithBuiltins[int](nil), WithConstants[int](nil)},
+			wantErr:    false,
+		},
+		{
+			name:       "BuiltinFunctions of another Number type",
+			operations: base.Operations,
+			opGroups:   base.OperationGroups,
+			opts:       []Option{WithBuiltins([]BuiltinFunction[float64]{{Name: "f", Fn: func(args ...float64) (float64, error) { return 0, nil }}})},
+			wantErr:    true,
+		},
+		{
+			name:       "constants of another Number type",
+			operations: base.Operations,
+			opGroups:   base.OperationGroups,
+			opts:       []Option{WithConstants(map[string]float64{"pi": 3.14})},
+			wantErr:    true,
+		},
+		{
+			name:       "Environment of another Number type",
+			operations: base.Operations,
+			opGroups:   base.OperationGroups,
+			opts:       []Option{WithEnvironment(NewEnvironment[float64]())},
+			wantErr:    true,
+		},
+		{
+			name:       "Trace of another Number type",
+			operations: base.Operations,
+			opGroups:   base.OperationGroups,
+			opts:       []Option{WithTrace(&Trace[float64]{})},
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewParser(tt.operations, tt.opGroups, tt.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewParser() err=%v wantErr=%v", err, tt.wantErr)
+			}
+			if tt.wantErr && !errors.Is(err, errInvalidConfiguration) {
+				t.Fatalf("NewParser() err=%v, want errInvalidConfiguration", err)
+			}
+		})
+	}
+}